@@ -0,0 +1,164 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBareQuote is the error of a row with a quote in an unquoted field,
+// or with a quote that is not escaped in a quoted field (like in "a"b").
+// It is reported only if WithSkipBadRows is used.
+var ErrBareQuote = errors.New("bare quote in field")
+
+// ErrUnterminatedQuote is the error of a row with a quoted field that is not closed at the end of the input.
+// It is reported by the scanner only if WithSkipBadRows is used, and it is returned by SplitLine and ParseField.
+var ErrUnterminatedQuote = errors.New("unterminated quoted field")
+
+// ErrFieldCount is the error of a row with a number of fields different from the first data row.
+// It is reported only if WithSkipBadRows is used.
+var ErrFieldCount = errors.New("wrong number of fields in row")
+
+// MultiError is the error returned by Err() if some rows were skipped with WithSkipBadRows.
+// It contains the errors (*ScanError) of the skipped rows, followed by the error that stopped the scan, if any.
+type MultiError []error
+
+func (e MultiError) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// WithSkipBadRows makes the scanner check the rows and skip the bad ones instead of returning them.
+// A row is bad if it has a bare quote (ErrBareQuote), a quoted field that is not closed (ErrUnterminatedQuote)
+// or a number of fields different from the first data row (ErrFieldCount), the comments and the empty lines being ignored.
+// The rows with too many fields for WithMaxColumns (ErrTooManyColumns) or with a NUL byte for WithRejectNulls (ErrNull)
+// are skipped too, instead of stopping the scan.
+// For each skipped row fn is called with the line of the error and the error (a *ScanError), and then
+// the scan continues at the next row. At the end, Err() returns a MultiError with these errors.
+// The rows are buffered (as with WithRowBuffering), so no field of a skipped row is returned.
+func WithSkipBadRows(fn func(line int, err error)) Option {
+	return func(s *scanner) {
+		s.badRows = fn
+		s.buffering = true
+	}
+}
+
+// recoverable returns true if err is the error of a single row, that could be skipped (see WithSkipBadRows).
+func recoverable(err error) bool {
+	e, ok := err.(*ScanError)
+	if !ok {
+		return false
+	}
+	switch e.Err {
+	case ErrBareQuote, ErrUnterminatedQuote, ErrFieldCount, ErrTooManyColumns, ErrNull:
+		return true
+	}
+	return false
+}
+
+// skipRest skips the remaining fields of the row, after an error stopped its scan.
+func (s *scanner) skipRest() {
+	s.skipping = true
+	for !s.atRowEnd && s.scanField() {
+	}
+	s.skipping = false
+	// the next field starts a new row, even at the end of the input
+	s.atRowEnd = true
+}
+
+// uncountRow removes the skipped row from RowCount() and ColumnCountHistogram(), if it was counted.
+func (s *scanner) uncountRow(rowCount int) {
+	if s.rowCount == rowCount {
+		return
+	}
+	s.rowCount = rowCount
+	if s.colsHist != nil {
+		if s.colsHist[s.cols]--; s.colsHist[s.cols] == 0 {
+			delete(s.colsHist, s.cols)
+		}
+	}
+}
+
+// skipRow reports the buffered row if it is bad, with bad its first error (nil if none).
+// It returns true if the row should be skipped.
+func (s *scanner) skipRow(bad *ScanError) bool {
+	if bad == nil {
+		if len(s.rowFields) == 0 {
+			return false
+		}
+		err := s.checkFieldCount()
+		if err == nil {
+			return false
+		}
+		first := s.rowFields[0].state
+		bad = &ScanError{Line: first.line, Offset: first.offset, Err: err}
+	}
+	s.badErrs = append(s.badErrs, bad)
+	s.badRows(bad.Line, bad)
+	return true
+}
+
+// checkFieldCount returns ErrFieldCount if the buffered row is a data row
+// with a number of fields different from the first data row.
+func (s *scanner) checkFieldCount() error {
+	if f := s.rowFields[0].state; len(s.rowFields) == 1 && (f.isComment || !f.isQuoted && s.empty(s.rowValues)) {
+		// a comment or an empty line
+		return nil
+	}
+	if s.dataCols == 0 {
+		s.dataCols = len(s.rowFields)
+	}
+	if len(s.rowFields) != s.dataCols {
+		return ErrFieldCount
+	}
+	return nil
+}
+
+// badQuote checks a part of the current field for a bare quote, if WithSkipBadRows is used.
+// In an unquoted field every quote is bare, in a quoted field the quotes should be escaped.
+// If a bare quote is found, it is the error of the row and true is returned.
+func (s *scanner) badQuote(data []byte) bool {
+	if s.badRows == nil || s.quote == 0 || s.quoteCollector == nil || s.isComment || s.rowErr != nil {
+		return false
+	}
+	bare := bytes.IndexByte(data, s.quote) >= 0
+	if s.isQuoted {
+		bare = hasBareQuote(data, s.quote, s.escape)
+	}
+	if bare {
+		s.rowErr = ErrBareQuote
+	}
+	return bare
+}
+
+// hasBareQuote returns true if data (a part of a quoted field without the bording quotes)
+// has a quote that is not escaped.
+func hasBareQuote(data []byte, quote, escape byte) bool {
+	for i := 0; i < len(data); i++ {
+		switch {
+		case escape == quote && data[i] == quote:
+			// the escaped quotes are doubled
+			if i+1 == len(data) || data[i+1] != quote {
+				return true
+			}
+			i++
+		case escape != 0 && data[i] == escape:
+			// skip the escaped byte
+			i++
+		case data[i] == quote:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSkipBadRows(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected string
+		lines    []int
+		rows     int
+	}{
+		{"a,b\nc,d\n", nil, "a|b|c|d", nil, 2},
+		{"a,b\nc\nd,e\n", nil, "a|b|d|e", []int{2}, 2},
+		{"a,b\nc,d,e\nf,g", nil, "a|b|f|g", []int{2}, 2},
+		{"# x\na,b\n\nc,d\n", nil, " x|a|b||c|d", nil, 2},
+		{"a\"b,c\nd,e\nf\n", nil, "d|e", []int{1, 3}, 1},
+		{"a,\"b\"x,c\nd,e\n", nil, "d|e", []int{1}, 1},
+		{"a,b\n\"c\"d\",e\nf,g\n", nil, "a|b|f|g", []int{2}, 2},
+		{"a,\"b\"\"c\"\n", nil, "a|b\"c", nil, 1},
+		{"a,\"b\\\"c\"\nd,\"e\"f\"\n", []Option{WithEscape('\\')}, "a|b\"c", []int{2}, 1},
+		{"a,b\nc,\"d\ne,f\n", nil, "a|b", []int{2}, 1},
+		{"a,\"b\nc\"\nd\ne,f\n", nil, "a|b\nc|e|f", []int{3}, 2},
+		{"a\"b,c\n", []Option{WithQuote(0, nil)}, "a\"b|c", nil, 1},
+		{"a,b\nc,d,e\nf,g\n", []Option{WithMaxColumns(2)}, "a|b|f|g", []int{2}, 2},
+		{"a,b,c,d\ne,f\ng,h,i", []Option{WithMaxColumns(2)}, "e|f", []int{1, 3}, 1},
+		{"a,\"b\nx\",c\nd,e\n", []Option{WithMaxColumns(2)}, "d|e", []int{2}, 1},
+		{"a,b\nc\x00,d\ne,f", []Option{WithRejectNulls()}, "a|b|e|f", []int{2}, 2},
+		{"a,b\n1,2,3\n", []Option{WithMaxColumns(2), WithQuote(0, nil), WithComment(nil)}, "a|b", []int{2}, 1},
+	}
+	for _, d := range data {
+		lines := []int{}
+		options := append(d.options, WithSkipBadRows(func(line int, err error) { lines = append(lines, line) }))
+		sc := New(strings.NewReader(d.in), options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != d.expected || fmt.Sprint(lines) != fmt.Sprint(d.lines) {
+			t.Errorf("for <%q> expected %q (bad lines %v), got %q (bad lines %v)", d.in, d.expected, d.lines, got, lines)
+		}
+		var merr MultiError
+		err := sc.Err()
+		if len(d.lines) == 0 && err != nil {
+			t.Errorf("for <%q> expected no error, got %v", d.in, err)
+		}
+		if len(d.lines) > 0 && (!errors.As(err, &merr) || len(merr) != len(d.lines)) {
+			t.Errorf("for <%q> expected %d errors, got %v", d.in, len(d.lines), err)
+		}
+		if sc.RowCount() != d.rows {
+			t.Errorf("for <%q> expected %d rows, got %d", d.in, d.rows, sc.RowCount())
+		}
+	}
+
+	sc := New(strings.NewReader("a,b\nc\n\"d\"e,f\n"), WithSkipBadRows(func(int, error) {}))
+	for sc.Scan() {
+	}
+	err := sc.Err()
+	if !errors.Is(err, ErrFieldCount) || !errors.Is(err, ErrBareQuote) {
+		t.Errorf("expected ErrFieldCount and ErrBareQuote, got %v", err)
+	}
+	if err.Error() != "2 errors: line 2, offset 4: wrong number of fields in row; line 3, offset 6: bare quote in field" {
+		t.Errorf("unexpected error message %q", err)
+	}
+
+	sc = New(strings.NewReader("a,b\nc,d,e\nf\n"), WithMaxColumns(2), WithColumnCountHistogram(), WithSkipBadRows(func(int, error) {}))
+	for sc.Scan() {
+	}
+	if err := sc.Err(); !errors.Is(err, ErrTooManyColumns) || err.Error() != "2 errors: line 2, offset 8: too many fields in row; line 3, offset 10: wrong number of fields in row" {
+		t.Errorf("expected ErrTooManyColumns at line 2 and ErrFieldCount at line 3, got %v", err)
+	}
+	if got := fmt.Sprint(sc.ColumnCountHistogram()); got != "map[2:1]" {
+		t.Errorf("expected map[2:1], got %s", got)
+	}
+}
@@ -0,0 +1,71 @@
+package scanner
+
+import (
+	"bufio"
+)
+
+// source is the interface of the underlying scanner that splits the input in chunks.
+// It is implemented by bufio.Scanner and bytesSource.
+type source interface {
+	Split(split bufio.SplitFunc)
+	Scan() bool
+	Bytes() []byte
+	Err() error
+}
+
+// bytesSource is a source that splits a byte slice without copying it.
+type bytesSource struct {
+	data  []byte          // the remaining data
+	split bufio.SplitFunc // the split function
+	token []byte          // the last chunk
+	done  bool            // true if the final chunk was delivered
+}
+
+func (b *bytesSource) Split(split bufio.SplitFunc) {
+	b.split = split
+}
+
+func (b *bytesSource) Scan() bool {
+	if b.done {
+		return false
+	}
+	// the capacity is limited, so that the split function can not append to the input
+	advance, token, err := b.split(b.data[:len(b.data):len(b.data)], true)
+	if err != nil || token == nil {
+		b.done = true
+	}
+	if token == nil {
+		return false
+	}
+	b.data = b.data[advance:]
+	b.token = token
+	return true
+}
+
+func (b *bytesSource) Bytes() []byte {
+	return b.token
+}
+
+func (b *bytesSource) Err() error {
+	return nil
+}
+
+// NewBytes returns a new Scanner that reads directly from data, without the copies done by New.
+// The unquoted fields returned by Bytes() are slices of data (that should not be modified),
+// and stay valid as long as data does (and not only until the next call to Scan()).
+// The quoted fields and the comments are copied, as for New.
+func NewBytes(data []byte, options ...Option) Scanner {
+	src := &bytesSource{data: data}
+	s := newScanner(src, options...)
+	s.zeroCopy = true
+	if n, big := utf16BOM(data); s.autoUTF16 && n > 0 {
+		// the decoded data is a new slice
+		in := data[n:]
+		data = appendUTF16(nil, &in, big, true)
+		src.data = data
+	}
+	if s.excelSep {
+		src.data = data[s.skipSepDirective(data):]
+	}
+	return s
+}
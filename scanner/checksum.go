@@ -0,0 +1,51 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// checksumPrefix starts the comment with the checksum written by the writer option WithChecksumComment.
+var checksumPrefix = []byte("checksum: ")
+
+// VerifyChecksum reads r and checks the last comment "checksum: <hex>" written by the writer option WithChecksumComment,
+// against the sum computed by h (that should be the same hash as for writing) over the data rows.
+// Each field value is followed by the byte 0x1F and each row by the byte 0x1E, the comments and the blank rows (see IsBlankRow) are skipped.
+// The options are the same as for New. It returns false if there is no checksum comment,
+// if the sum differs or if some data row follows the checksum comment.
+func VerifyChecksum(r io.Reader, h hash.Hash, opts ...Option) (bool, error) {
+	sc := New(r, opts...)
+	ok := false
+	var buf []byte
+	for {
+		row, more := sc.NextRow()
+		if !more {
+			break
+		}
+		switch {
+		case row.IsComment():
+			text := bytes.TrimSpace(row.Fields()[0])
+			if sum, found := bytes.CutPrefix(text, checksumPrefix); found {
+				ok = string(sum) == hex.EncodeToString(h.Sum(nil))
+			}
+		case IsBlankRow(row.Fields()):
+		default:
+			buf = buf[:0]
+			for _, f := range row.Fields() {
+				buf = append(append(buf, f...), 0x1F)
+			}
+			h.Write(append(buf, 0x1E))
+			ok = false
+		}
+	}
+	return ok, sc.Err()
+}
+
+// IsBlankRow returns true if the row has no field or a single field made only of spaces and tabs, quoted or not.
+// Such rows are read as empty lines, so they are not part of the checksum
+// written by the writer option WithChecksumComment and checked by VerifyChecksum.
+func IsBlankRow(fields [][]byte) bool {
+	return len(fields) == 0 || len(fields) == 1 && onlyWhiteSpaces(fields[0])
+}
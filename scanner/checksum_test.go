@@ -0,0 +1,36 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	// crc32 of "a\x1fb\x1f\x1e1\x1f2,3\x1f\x1e"
+	sum := crc32.NewIEEE()
+	sum.Write([]byte("a\x1fb\x1f\x1e1\x1f2,3\x1f\x1e"))
+	checksum := hex.EncodeToString(sum.Sum(nil))
+	data := []struct {
+		in       string
+		expected bool
+	}{
+		{"a,b\n# note\n\n1,\"2,3\"\n# checksum: " + checksum + "\n", true},
+		{"a,b\n1,\"2,3\"\n#checksum: " + checksum, true},
+		{"a,b\n1,\"2,4\"\n# checksum: " + checksum + "\n", false},
+		{"a,b\n1,\"2,3\"\n# checksum: " + checksum + "\nx\n", false},
+		{"a,b\n1,\"2,3\"\n", false},
+	}
+	for _, d := range data {
+		ok, err := VerifyChecksum(strings.NewReader(d.in), crc32.NewIEEE())
+		if ok != d.expected || err != nil {
+			t.Errorf("for %q expected %v, got %v (error: %v)", d.in, d.expected, ok, err)
+		}
+	}
+	// another hash
+	if ok, _ := VerifyChecksum(strings.NewReader(data[0].in), sha256.New()); ok {
+		t.Errorf("expected a wrong sha256 checksum")
+	}
+}
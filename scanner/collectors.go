@@ -2,6 +2,7 @@ package scanner
 
 import (
 	"bytes"
+	"strings"
 )
 
 // collector interface used to collect chunks of data to a single field
@@ -25,6 +26,7 @@ type collector interface {
 // removeSeparator removes the separator from the chunk.
 // The last byte should be the separator.
 // If the separator is a newline, check for \r too.
+// The record separator, if not a newline, is removed as any other separator.
 // It is used by End of some quote collectors.
 func removeSeparator(chunk []byte) []byte {
 	if len(chunk) > 1 && chunk[len(chunk)-1] == '\n' && chunk[len(chunk)-2] == '\r' {
@@ -33,22 +35,60 @@ func removeSeparator(chunk []byte) []byte {
 	return chunk[:len(chunk)-1]
 }
 
+// defaultFuzzySpaces are the characters skipped around the quotes by the fuzzy quote collectors.
+const defaultFuzzySpaces = " \t"
+
+// fuzzyCutset returns the characters skipped around the quotes for the separator sep.
+// If the separator is a tab, the tabs are never skipped (only the separator could be a tab outside of the quotes).
+func fuzzyCutset(spaces string, sep byte) string {
+	if sep != '\t' {
+		return spaces
+	}
+	if spaces == defaultFuzzySpaces {
+		return " "
+	}
+	return strings.ReplaceAll(spaces, "\t", "")
+}
+
+// skipSpaces returns the index of the first character of chunk that is not in spaces.
+// As in bytes.TrimLeft, spaces is a set of UTF-8 characters.
+func skipSpaces(chunk []byte, spaces string) int {
+	return len(chunk) - len(bytes.TrimLeft(chunk, spaces))
+}
+
 // Comment Collector
 // -----------------
 
 type commentCollector struct {
 	Scanner
+	indent  bool   // true if spaces and tabs are allowed before the comment prefix
+	raw     bool   // true if the comment is returned with its indentation and prefix
+	matched []byte // the prefix of the last started comment
 }
 
+// Start checks all the comment prefixes, the longest matching one is used.
 func (c *commentCollector) Start(chunk []byte) ([]byte, bool) {
-	if bytes.HasPrefix(chunk, c.Comment()) {
-		return chunk[len(c.Comment()):], true
+	i := 0
+	if c.indent {
+		i = skipSpaces(chunk, defaultFuzzySpaces)
+	}
+	c.matched = nil
+	for _, p := range c.Comments() {
+		if len(p) > len(c.matched) && bytes.HasPrefix(chunk[i:], p) {
+			c.matched = p
+		}
+	}
+	if c.matched != nil && c.raw {
+		return chunk, true
+	}
+	if c.matched != nil {
+		return chunk[i+len(c.matched):], true
 	}
 	return chunk, false
 }
 
 func (c *commentCollector) End(chunk []byte) ([]byte, bool) {
-	if bytes.HasSuffix(chunk, []byte{'\n'}) {
+	if len(chunk) > 0 && chunk[len(chunk)-1] == c.RecordSeparator() {
 		return removeSeparator(chunk), true
 	}
 	return chunk, false
@@ -58,7 +98,40 @@ func (c *commentCollector) End(chunk []byte) ([]byte, bool) {
 // It is used by the scanner to collect comments.
 // A comment is a line starting with the comment prefix.
 func newCommentCollector(s Scanner) collector {
-	return &commentCollector{s}
+	return &commentCollector{Scanner: s}
+}
+
+// Escaped Separator Collector
+// -----------------------------
+
+// sepCollector collects the unquoted fields containing escaped separators (like a\,b).
+// It is used by WithEscapedSeparators() scanner option.
+type sepCollector struct {
+	Scanner
+}
+
+// escapedSep returns true if the chunk ends with an escaped separator.
+// As for quoteCollector.end, the escape parity depends only on the current chunk.
+func (c *sepCollector) escapedSep(chunk []byte) bool {
+	if len(chunk) == 0 || chunk[len(chunk)-1] != c.Separator() || c.Separator() == c.RecordSeparator() {
+		return false
+	}
+	escaped := false
+	for i := len(chunk) - 2; i >= 0 && chunk[i] == c.Escape(); i-- {
+		escaped = !escaped
+	}
+	return escaped
+}
+
+func (c *sepCollector) Start(chunk []byte) ([]byte, bool) {
+	return chunk, c.escapedSep(chunk)
+}
+
+func (c *sepCollector) End(chunk []byte) ([]byte, bool) {
+	if c.escapedSep(chunk) {
+		return chunk, false
+	}
+	return removeSeparator(chunk), true
 }
 
 // quoteType is a function that returns a new quote collector for a giver Scanner
@@ -76,6 +149,8 @@ type quoteCollector struct {
 
 // end checks if the chunk ends with an unescaped quote, in which case it removes it.
 // It is used by End of some derived quote collectors.
+// The escape parity depends only on the current chunk: bufio.Scanner never splits a chunk,
+// and the byte before a chunk is a separator (or the opening quote), that is not an escape character.
 func (c *quoteCollector) end(chunk []byte) ([]byte, bool) {
 	if len(chunk) == 0 || chunk[len(chunk)-1] != c.Quote() {
 		return chunk, false
@@ -104,6 +179,8 @@ func (c *quoteCollectorStrict) Start(chunk []byte) ([]byte, bool) {
 	return chunk, false
 }
 
+// End accepts no spaces between the closing quote and the separator,
+// but a closing quote followed by "\r\n" is recognized (removeSeparator strips the '\r').
 func (c *quoteCollectorStrict) End(chunk []byte) ([]byte, bool) {
 	if v, ok := c.end(removeSeparator(chunk)); ok {
 		return v, true
@@ -124,18 +201,18 @@ func quoteStrict(s Scanner) collector {
 // -----------------------
 
 // Some spaces are allowed before and after the separator.
-// If tab is used as separator, then we can't find tabs outisde of the quotes.
+// If tab is used as separator, then we can't find tabs outisde of the quotes,
+// so only the spaces are skipped (see fuzzyCutset).
 // If the separator is a space, this collector make no sens because is equivalent to the strict collector.
-// So tab is always treated as space.
+// The skipped characters can be changed with WithFuzzyWhitespace() scanner option.
 type quoteCollectorFuzzy struct {
 	quoteCollector
+	spaces string // characters skipped around the quotes (default " \t")
+	cutset string // spaces without the separator, set by the scanner options (see fuzzyCutset)
 }
 
 func (c *quoteCollectorFuzzy) Start(chunk []byte) ([]byte, bool) {
-	i := 0
-	for i < len(chunk) && (chunk[i] == ' ' || chunk[i] == '\t') {
-		i++
-	}
+	i := skipSpaces(chunk, c.cutset)
 	if i < len(chunk) && chunk[i] == c.Quote() {
 		return chunk[i+1:], true
 	}
@@ -143,12 +220,8 @@ func (c *quoteCollectorFuzzy) Start(chunk []byte) ([]byte, bool) {
 }
 
 func (c *quoteCollectorFuzzy) End(chunk []byte) ([]byte, bool) {
-	v := removeSeparator(chunk)
-	i := len(v) - 1
-	for i >= 0 && (v[i] == ' ' || v[i] == '\t') {
-		i--
-	}
-	if v, ok := c.end(v[:i+1]); ok {
+	v := bytes.TrimRight(removeSeparator(chunk), c.cutset)
+	if v, ok := c.end(v); ok {
 		return v, true
 	}
 	return chunk, false
@@ -161,5 +234,56 @@ var QuoteFuzzy quoteType = quoteFuzzy
 
 // quoteFuzzy is QuoteFuzzy but hidden from the doc.
 func quoteFuzzy(s Scanner) collector {
-	return &quoteCollectorFuzzy{quoteCollector{s}}
+	return &quoteCollectorFuzzy{quoteCollector{s}, defaultFuzzySpaces, fuzzyCutset(defaultFuzzySpaces, s.Separator())}
+}
+
+// Quote Collector : Pair
+// ----------------------
+
+// quoteCollectorPair is a quote collector where the opening and closing quotes differ
+// and could have more than one byte (like “ and ”).
+// It is used by WithQuotePair() scanner option.
+type quoteCollectorPair struct {
+	Scanner
+	open   []byte // opening quote
+	close  []byte // closing quote
+	spaces string // characters skipped around the quotes (empty in strict mode)
+	cutset string // spaces without the separator, set by the scanner options (see fuzzyCutset)
+}
+
+func (c *quoteCollectorPair) Start(chunk []byte) ([]byte, bool) {
+	i := skipSpaces(chunk, c.cutset)
+	if bytes.HasPrefix(chunk[i:], c.open) {
+		return chunk[i+len(c.open):], true
+	}
+	return chunk, false
+}
+
+func (c *quoteCollectorPair) End(chunk []byte) ([]byte, bool) {
+	v := bytes.TrimRight(removeSeparator(chunk), c.cutset)
+	if !bytes.HasSuffix(v, c.close) {
+		return chunk, false
+	}
+	v = v[:len(v)-len(c.close)]
+	// check if the closing quote is escaped
+	escaped := false
+	if esc := c.Escape(); esc != 0 {
+		for i := len(v) - 1; i >= 0 && v[i] == esc; i-- {
+			escaped = !escaped
+		}
+	} else {
+		for u := v; bytes.HasSuffix(u, c.close); u = u[:len(u)-len(c.close)] {
+			escaped = !escaped
+		}
+	}
+	if escaped {
+		return chunk, false
+	}
+	return v, true
+}
+
+// newQuoteCollectorPair returns a new quote collector with distinct opening and closing quotes.
+// The characters in spaces are skipped around the quotes (use "" for strict mode).
+func newQuoteCollectorPair(s Scanner, open, close []byte, spaces string) collector {
+	return &quoteCollectorPair{s, open, close, spaces, fuzzyCutset(spaces, s.Separator())}
 }
@@ -0,0 +1,62 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColumnType is the type of the values of a column.
+type ColumnType int
+
+const (
+	TypeString ColumnType = iota // default type
+	TypeInt
+	TypeFloat
+	TypeBool
+)
+
+// columnTypeNames are the type tokens used in typed headers (see ParseTypedHeader).
+var columnTypeNames = map[string]ColumnType{
+	"string": TypeString,
+	"int":    TypeInt,
+	"float":  TypeFloat,
+	"bool":   TypeBool,
+}
+
+// String returns the type token of the column type (like "int").
+func (t ColumnType) String() string {
+	for name, ct := range columnTypeNames {
+		if ct == t {
+			return name
+		}
+	}
+	return fmt.Sprintf("ColumnType(%d)", int(t))
+}
+
+// ParseTypedHeader splits each header field like "count:int" into a name and a column type.
+// Only the last colon is used as a separator, so names can contain colons.
+// The type tokens are "string", "int", "float" and "bool" (case insensitive).
+// If no type is given, TypeString is used.
+// An error is returned if some type token is unknown.
+func ParseTypedHeader(header []string) ([]string, []ColumnType, error) {
+	names := make([]string, len(header))
+	types := make([]ColumnType, len(header))
+	for i, field := range header {
+		names[i] = field
+		n := strings.LastIndexByte(field, ':')
+		if n == -1 {
+			continue
+		}
+		names[i] = field[:n]
+		token := strings.ToLower(strings.TrimSpace(field[n+1:]))
+		if token == "" {
+			continue
+		}
+		t, ok := columnTypeNames[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown type %q for column %q", field[n+1:], names[i])
+		}
+		types[i] = t
+	}
+	return names, types, nil
+}
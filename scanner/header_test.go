@@ -0,0 +1,34 @@
+package scanner
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseTypedHeader(t *testing.T) {
+	data := []struct {
+		in    []string
+		names []string
+		types []ColumnType
+		err   bool
+	}{
+		{[]string{}, []string{}, []ColumnType{}, false},
+		{[]string{"time:float", "name:string", "count:int", "ok:bool"}, []string{"time", "name", "count", "ok"}, []ColumnType{TypeFloat, TypeString, TypeInt, TypeBool}, false},
+		{[]string{"name", "a:b:INT", "c:"}, []string{"name", "a:b", "c"}, []ColumnType{TypeString, TypeInt, TypeString}, false},
+		{[]string{"a:int", "b:date"}, nil, nil, true},
+	}
+	for _, d := range data {
+		names, types, err := ParseTypedHeader(d.in)
+		if fmt.Sprint(names, types) != fmt.Sprint(d.names, d.types) || (err != nil) != d.err {
+			t.Errorf("for %q expected %q %v (error: %v), got %q %v (error: %v)", d.in, d.names, d.types, d.err, names, types, err)
+		}
+	}
+}
+
+func TestColumnTypeString(t *testing.T) {
+	for ct, name := range map[ColumnType]string{TypeString: "string", TypeInt: "int", TypeFloat: "float", TypeBool: "bool", 42: "ColumnType(42)"} {
+		if got := ct.String(); got != name {
+			t.Errorf("expected %q, got %q", name, got)
+		}
+	}
+}
@@ -3,23 +3,42 @@ package scanner
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
+	"unsafe"
 )
 
 // Scanner interface
 type Scanner interface {
 	// Separator returns the separator character (like ',', ';' or '\t').
 	Separator() byte
-	// Quote returns the quote character (like '"' or "'" or 0 if not quoted).
+	// RecordSeparator returns the character that terminates the rows (default '\n').
+	RecordSeparator() byte
+	// Quote returns the quote character (like '"' or "'" or 0 if not quoted or if WithQuotePair is used).
 	Quote() byte
 	// Escape returns the escape character (like '"' or '\' or 0 if not quoted).
+	// If WithQuotePair is used, 0 means that the closing quote is escaped by doubling it.
 	Escape() byte
 	// Comment returns the comment prefix (like '#' or '\\' or nil if no comment).
+	// If several prefixes are set with WithComments, the first one is returned.
 	Comment() []byte
+	// Comments returns all the comment prefixes (nil if no comment).
+	Comments() [][]byte
 
 	// Scan recover next field, if false then error or end of file is reached.
 	Scan() bool
+	// ScanUntil is Scan, but it returns false if pred returns true for the scanned field (that is still returned by Bytes()).
+	// The following fields are not read, and the next call continues after the matching field.
+	// Err() is nil after a stop on a match, so pred should record the match to distinguish it from the end of file.
+	ScanUntil(pred func(field []byte, atRowEnd bool) bool) bool
+	// RowColumns returns the number of fields of the current row.
+	// With WithRowBuffering() it is known from the first field of the row,
+	// otherwise it is known only at the last field of the row and -1 is returned before.
+	RowColumns() int
 	// Err() returns the first non-EOF error that was encountered by the Scanner.
+	// Errors from the underlying reader are wrapped in a *ScanError.
 	Err() error
 
 	// Bytes return the current field as a byte slice.
@@ -28,72 +47,275 @@ type Scanner interface {
 	// Comment fields are returned without the comment prefix.
 	// This value is valid only until the next call to Scan().
 	Bytes() []byte
+	// CurrentRow returns the current field and the following fields up to the end of the row,
+	// leaving the scanner at the last field of the row.
+	// With WithIgnoreTrailingSeparator(), an empty unquoted last field is dropped.
+	// It should be called after a successful Scan(), in general at the row start.
+	// The returned slices are valid only until the next call to CurrentRow().
+	CurrentRow() [][]byte
+	// NextRow scans the next row and returns it, if false then error or end of file is reached.
+	// It should be called at the end of a row (or before the first Scan()).
+	// The fields of the returned Row are valid only until the next call to NextRow() or CurrentRow().
+	NextRow() (Row, bool)
+	// ReadInto reads the next data row (skipping the comments and the empty lines) into dst,
+	// reusing its memory. It returns io.EOF at the end of the input.
+	// Only the columns set by WithColumns() are read, if any.
+	ReadInto(dst *[]string) error
+	// ReadCellsInto is ReadInto, but each field is a cell, that is a list of values.
+	// The fields of the columns set by WithSubSeparator() are split, the other cells have a single value.
+	ReadCellsInto(dst *[][]string) error
 	// Offset returns the offset in bytes of the current field in the input.
 	Offset() int
+	// RowCount returns the number of data rows completed so far (the comments and the empty lines are not counted).
+	// The current row is counted when its last field is scanned (AtRowEnd() is true).
+	RowCount() int
+	// PhysicalLine returns the number (starting at 1) of the line where the current field starts in the input,
+	// as shown by a text editor: all the line breaks (or record separators) are counted, including those inside the quoted fields.
+	PhysicalLine() int
+	// RecordNumber returns the number (starting at 1) of the data row of the current field,
+	// that is the logical row, whatever the number of lines it spans (0 for the comments and the empty lines).
+	// It is the value of RowCount() once the row is completed.
+	RecordNumber() int
+	// ColumnCountHistogram returns the number of data rows for each number of fields,
+	// counted so far (nil if WithColumnCountHistogram() is not used).
+	ColumnCountHistogram() map[int]int
 
 	// AtRowStart returns true if the current field is the first field of the row.
 	AtRowStart() bool
 	// AtRowEnd returns true if the current field is the last field of the row.
 	AtRowEnd() bool
+	// EndedWithNewline returns true if the current field is the last field of the row
+	// and is followed by a line break (or a record separator) in the input.
+	// It is false for the last row if the input does not end with a line break.
+	EndedWithNewline() bool
+	// Truncated returns true if the input ends in the middle of a row:
+	// the last row has no line break (or record separator), or its last quoted field (or comment) is not closed.
+	// It should be called after Scan() returns false, to distinguish a cut input from a clean end.
+	Truncated() bool
 
 	// IsComment returns true if the current field is a comment.
 	IsComment() bool
+	// CommentPrefix returns the comment prefix that starts the current field (nil if it is not a comment).
+	CommentPrefix() []byte
 	// IsQuoted returns true if the current field is quoted.
 	IsQuoted() bool
+	// QuoteSpans returns the offsets in the input of the opening and closing quotes of the current field.
+	// Both are -1 if the field is not quoted, and closeAt is -1 if the closing quote is missing (end of file).
+	QuoteSpans() (openAt, closeAt int)
 	// IsEmptyLine returns true if the current field is an empty line.
 	IsEmptyLine() bool
 }
 
+// Row is a row returned by NextRow().
+type Row struct {
+	fields  [][]byte
+	comment bool
+	empty   bool
+	quoted  bool // true if the first field is enquoted (so an empty row `""` is not a blank line)
+	line    int
+}
+
+// Fields returns the fields of the row (a single field for comments and empty lines).
+// The fields are valid only until the next call to NextRow() or CurrentRow().
+func (r Row) Fields() [][]byte {
+	return r.fields
+}
+
+// IsComment returns true if the row is a comment.
+func (r Row) IsComment() bool {
+	return r.comment
+}
+
+// IsEmpty returns true if the row is an empty line.
+func (r Row) IsEmpty() bool {
+	return r.empty
+}
+
+// IsQuoted returns true if the first field of the row is quoted,
+// so an empty row `""` is a data row with a single empty field even if IsEmpty() is true.
+func (r Row) IsQuoted() bool {
+	return r.quoted
+}
+
+// Line returns the line number (starting at 1) where the row starts.
+func (r Row) Line() int {
+	return r.line
+}
+
 // scanner is the default implementation of Scanner.
 // It trats only the standard case (no space separated fields)
 type scanner struct {
 	// Parameters
-	src     bufio.Scanner // source scanner that scans to separator or end of line
-	sep     byte          // separator character (default ',')
-	quote   byte          // quote character (default '"')
-	escape  byte          // escape character (default '"')
-	comment []byte        // comment characters (default "#")
+	src     source // source scanner that scans to separator or end of line
+	sep     byte   // separator character (default ',')
+	rs      byte   // record separator character (default '\n')
+	quote   byte   // quote character (default '"')
+	escape  byte   // escape character (default '"')
+	comment []byte // comment characters (default "#")
+
+	comments [][]byte // all the comment prefixes (see WithComments), comment is the first one
+
+	nulls nullMode // how the NUL bytes in the fields are handled (default nullKeep)
+
+	maxColumns int // maximal number of fields in a row (0 if no limit)
+
+	decimalComma   bool // true if the decimal comma of the numeric fields is replaced by a dot
+	normalizeEOL   bool // true if "\r\n" and '\r' are replaced by '\n' in the fields
+	skipBOM        bool // true if the UTF-8 BOM at the start of the input is skipped
+	excelSep       bool // true if a leading "sep=X" line sets the separator (see WithExcelSepDirective)
+	autoUTF16      bool // true if the input starting with a UTF-16 BOM is decoded (see WithAutoUTF16)
+	keepAfterClose bool // true if the spaces after the closing quote are kept (see WithFuzzyTrimAfterCloseOnly)
+
+	stopMarker []byte // the comment text that stops the scanning (nil if none)
+	stopped    bool   // true if the stop marker was reached
+
+	chunkObserver func(chunk []byte, atRowEnd bool) // called for each chunk read from the source (nil if none)
+
+	colsHist map[int]int // number of data rows for each number of fields (nil if not collected)
+
+	unsafeStrings bool  // true if the strings returned by ReadInto() alias the internal buffer
+	keepEmpty     bool  // true if ReadInto() returns the empty lines as rows without fields
+	columns       []int // indices of the fields returned by ReadInto() (nil for all the fields)
+
+	subsep     byte         // sub-separator of the lists in the cells returned by ReadCellsInto()
+	subColumns map[int]bool // indices of the fields split by ReadCellsInto()
+	cellRow    []string     // the row read by ReadCellsInto()
+
+	ignoreTrailingSep bool // true if CurrentRow() drops the empty unquoted last field of the rows
+	ignoreLeadingSep  bool // true if Scan() skips the empty unquoted first field of the rows
+	afterLeadingSep   bool // true while the field after a skipped leading separator is scanned (it is not a comment)
+
+	ragged       func(fields [][]byte, expected int) [][]byte // reshapes the rows with a wrong number of fields (nil if none)
+	expectedCols int                                          // number of fields of the first data row (used by ragged)
+
+	headerFn   func(string) string // transforms the fields of the first data row returned by NextRow() (nil if none)
+	headerRow  [][]byte            // the transformed header (used only if headerFn is not nil)
+	headerDone bool                // true if the first data row was returned by NextRow()
+
+	skipHeader bool     // true if the rows equal to the header are skipped by NextRow()
+	header     [][]byte // copy of the first data row (used only if skipHeader is true)
+
+	quoteOpen  []byte // opening quote if set with WithQuotePair (nil otherwise)
+	quoteClose []byte // closing quote if set with WithQuotePair (nil otherwise)
 
 	// Collectors
 	quoteCollector   collector
 	commentCollector collector
+	sepCollector     collector // collects the unquoted fields with escaped separators (nil if not used)
 
 	// check if the field is empty (depends on the separator)
 	empty func([]byte) bool
 
+	zeroCopy bool   // true if the unquoted fields could alias the input (see NewBytes)
+	plain    bool   // true if no option needs the general scan (see isPlain)
+	buf      []byte // buffer used to build the field values that do not alias the input
+
+	// Bad rows handling (see WithSkipBadRows)
+	badRows  func(line int, err error) // called for each skipped row (nil if the rows are not checked)
+	badErrs  []error                   // the errors of the skipped rows (returned by Err() in a MultiError)
+	rowErr   error                     // the error of the current row found by Scan()
+	dataCols int                       // number of fields of the first data row (0 before it)
+	skipping bool                      // true while the rest of a bad row is skipped
+
 	// State variables that are set during scanning
+	fieldState
+	atEOF     bool  // true if the last chunk is terminated by the end of file (and not by a separator)
+	lines     int   // number of line ends (record separators) read so far
+	truncated bool  // true if the last row is not terminated (see Truncated)
+	err       error // the error returned by Err()
+
+	// Buffers used by WithRowBuffering()
+	buffering  bool            // true if the whole row is read on its first field
+	rowFields  []bufferedField // the fields of the buffered row
+	rowValues  []byte          // the concatenated values of the buffered row
+	rowNext    int             // index in rowFields of the next field to deliver
+	rowPending error           // error that stopped the buffering of the row (reported after its last field)
+
+	// Buffers used by CurrentRow()
+	rowBuf  []byte   // the concatenated fields of the row
+	rowEnds []int    // the end of each field in rowBuf
+	row     [][]byte // the fields of the row (slices of rowBuf)
+}
+
+// fieldState is the state of the current field, set by each call to Scan().
+type fieldState struct {
 	value      []byte // the field value returned by Bytes() (without delimiters, comment prefix, bording quotes and escapes)
 	rawlen     int    // length of the raw value (including quotes and separator) used only to compute offset
 	offset     int    // offset of the field in the input (starting at 0)
 	isComment  bool   // true if the field is a comment
 	isQuoted   bool   // true if the field is enquoted (first and last bytes are quotes)
+	openAt     int    // offset of the opening quote in the input (-1 if not quoted)
+	closeAt    int    // offset of the closing quote in the input (-1 if not quoted or not closed)
 	atRowStart bool   // true if the field is the first one in the row
 	atRowEnd   bool   // true if the field is the last one in the row
+	line       int    // line number (starting at 1) of the current field
+	cols       int    // number of fields of the current row scanned so far
+	rowCount   int    // number of data rows completed so far (returned by RowCount())
+}
+
+// bufferedField is a field saved by the row buffering (see WithRowBuffering).
+type bufferedField struct {
+	state  fieldState // the state of the field (the value is set from rowValues when delivered)
+	end    int        // the end of the value in rowValues
+	prefix []byte     // the comment prefix (see CommentPrefix)
+}
+
+// ScanError is the error returned by Err() when the underlying bufio.Scanner fails
+// (like bufio.ErrTooLong or a read error).
+type ScanError struct {
+	Line   int   // line number (starting at 1) where the error occurred
+	Offset int   // offset in bytes of the data that could not be read
+	Err    error // the underlying error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("line %d, offset %d: %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *ScanError) Unwrap() error {
+	return e.Err
 }
 
+// ErrNull is the error (wrapped in a *ScanError) returned by Err()
+// if a field contains a NUL byte and WithRejectNulls() is used.
+var ErrNull = errors.New("NUL byte in field")
+
+// ErrTooManyColumns is the error (wrapped in a *ScanError) returned by Err()
+// if a row has more fields than the limit set by WithMaxColumns().
+var ErrTooManyColumns = errors.New("too many fields in row")
+
+// nullMode is the way NUL bytes in the fields are handled.
+type nullMode int
+
+const (
+	nullKeep   nullMode = iota // NUL bytes are kept in the fields
+	nullStrip                  // NUL bytes are removed from the fields
+	nullReject                 // NUL bytes stop the scan with an error
+)
+
 // sepScan is a function that returns a split function for bufio.Scanner.
-// This function stops at the first separator or line end.
-// All fields end with a delimiter or newline (`\n`).
-// The last field of the last row is always followed by a `\n` (even if it's empty or missing).
-// If the separator is `\n` or 0, only the end of line is used as a separator.
-func sepScan(s byte) bufio.SplitFunc {
+// This function stops at the first separator s or line end rs (the record separator).
+// All fields end with a delimiter or newline (`\n` or rs).
+// The last field of the last row is always followed by a `\n` or rs (even if it's empty or missing).
+// If the separator is rs or 0, only the end of line is used as a separator.
+func sepScan(s, rs byte) bufio.SplitFunc {
 	// indexAny looks for the first separator or end of line
 	// it could be implemented with bytes.IndexAny but it's faster (I think) this way
 	// check https://github.com/golang/go/issues/60550
 	var indexAny func(data []byte) int
 	switch s {
-	case '\n', 0:
+	case rs, 0:
 		// no separator, only end of line
 		indexAny = func(data []byte) int {
-			return bytes.IndexByte(data, '\n')
+			return bytes.IndexByte(data, rs)
 		}
 	default:
 		indexAny = func(data []byte) int {
 			sepi := bytes.IndexByte(data, s)
 			if sepi == -1 {
-				return bytes.IndexByte(data, '\n')
+				return bytes.IndexByte(data, rs)
 			}
-			if nli := bytes.IndexByte(data[:sepi], '\n'); uint(nli) < uint(sepi) {
+			if nli := bytes.IndexByte(data[:sepi], rs); uint(nli) < uint(sepi) {
 				return nli
 			}
 			return sepi
@@ -109,9 +331,9 @@ func sepScan(s byte) bufio.SplitFunc {
 			return i + 1, data[:i+1], nil
 		}
 		// If we're at EOF, the remaining data has no separator
-		// and is the last field. Return it with '\n appended.
+		// and is the last field. Return it with '\n (or rs) appended.
 		if atEOF {
-			data = append(data, '\n')
+			data = append(data, rs)
 			return 0, data, bufio.ErrFinalToken
 		}
 		// Request more data.
@@ -123,20 +345,35 @@ func sepScan(s byte) bufio.SplitFunc {
 // Only <esc><quote> → <quote> is done. The non escaped quotes are preserved.
 // The starting and ending quotes should be removed before calling this function,
 // but even if they are not, they will stay unchanged (as they are not escaped).
+// If WithQuotePair is used, <esc><close> → <close> is done, or <close><close> → <close>
+// if no escape character is set.
 func (s *scanner) unescapeQuotes() {
-	// if the field is not quoted, return it as is
-	if len(s.value) == 0 || s.escape == 0 {
+	if len(s.value) == 0 {
 		return
 	}
-	eq := []byte{s.Escape(), s.Quote()}
+	// the escaped quote and the length of the escape sequence
+	var eq []byte
+	el := 1
+	switch {
+	case s.quoteClose != nil && s.escape == 0:
+		eq = append(append([]byte(nil), s.quoteClose...), s.quoteClose...)
+		el = len(s.quoteClose)
+	case s.quoteClose != nil:
+		eq = append([]byte{s.escape}, s.quoteClose...)
+	case s.escape == 0:
+		// no escape character, return the field as is
+		return
+	default:
+		eq = []byte{s.Escape(), s.Quote()}
+	}
 	n, m := 0, 0
 	for {
 		m = bytes.Index(s.value[n:], eq)
 		if m == -1 {
 			break
 		}
-		s.value = append(s.value[:n+m], s.value[n+m+1:]...)
-		n += m + 1
+		s.value = append(s.value[:n+m], s.value[n+m+el:]...)
+		n += m + len(eq) - el
 	}
 }
 
@@ -176,7 +413,7 @@ func WithSeparator(sep byte) Option {
 	return func(s *scanner) {
 		s.sep = sep
 		// set the split function for bufio.Scanner
-		s.src.Split(sepScan(sep))
+		s.setSplit()
 		// set the empty function to check if a field is empty
 		switch sep {
 		case ' ':
@@ -189,23 +426,147 @@ func WithSeparator(sep byte) Option {
 	}
 }
 
+// WithRecordSeparator sets the character that terminates the rows (default '\n').
+// It could be used to read ASCII delimited text (WithSeparator(0x1F) and WithRecordSeparator(0x1E)).
+// If the record separator is not '\n', then '\n' and '\r' are ordinary characters.
+// As for '\n', a quoted field could contain the record separator without ending the row.
+func WithRecordSeparator(rs byte) Option {
+	return func(s *scanner) {
+		s.rs = rs
+		// set the split function for bufio.Scanner
+		s.setSplit()
+	}
+}
+
+// setSplit sets the split function of the underlying bufio.Scanner
+// using the current separator and record separator.
+// It also keeps track of the final chunk that is not terminated by a separator.
+func (s *scanner) setSplit() {
+	split := sepScan(s.sep, s.rs)
+	s.src.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		if token != nil {
+			s.atEOF = err == bufio.ErrFinalToken
+		}
+		return advance, token, err
+	})
+}
+
 // WithQuote sets the quote and escape characters and the quote type.
 // The quote type could be QuoteStrict or QuoteFuzzy.
 // If quote is 0 or qt is nil, no unquoting is done.
+// The characters set by WithFuzzyWhitespace are kept if QuoteFuzzy is used again.
 func WithQuote(quote byte, qt quoteType) Option {
 	return func(s *scanner) {
+		spaces := defaultFuzzySpaces
+		switch c := s.quoteCollector.(type) {
+		case *quoteCollectorFuzzy:
+			spaces = c.spaces
+		case *quoteCollectorPair:
+			if c.spaces != "" {
+				spaces = c.spaces
+			}
+		}
 		s.quote = quote
 		s.escape = quote
+		s.quoteOpen = nil
+		s.quoteClose = nil
 		if quote != 0 && qt != nil {
 			s.quoteCollector = qt(s)
+			if c, ok := s.quoteCollector.(*quoteCollectorFuzzy); ok {
+				c.spaces = spaces
+			}
 		} else {
 			s.quoteCollector = nil
 		}
 	}
 }
 
-// WithEscape sets the escape character.
-// It should be called after WithQuote and only if it is different from the quote character.
+// WithQuotePair sets different opening and closing quotes (like “ and ”).
+// Multi-byte (UTF-8) quotes are allowed.
+// The quote type set by WithQuote (QuoteStrict or QuoteFuzzy) is kept, QuoteFuzzy is used if no quote type is set.
+// Inside such fields the closing quote is escaped by doubling it,
+// except if WithEscape is called after WithQuotePair to set an escape character.
+// If open or close is empty, no unquoting is done.
+func WithQuotePair(open, close []byte) Option {
+	return func(s *scanner) {
+		spaces := defaultFuzzySpaces
+		switch c := s.quoteCollector.(type) {
+		case *quoteCollectorStrict:
+			spaces = ""
+		case *quoteCollectorFuzzy:
+			spaces = c.spaces
+		}
+		s.quote = 0
+		s.escape = 0
+		s.quoteOpen = nil
+		s.quoteClose = nil
+		s.quoteCollector = nil
+		if len(open) > 0 && len(close) > 0 {
+			s.quoteOpen = open
+			s.quoteClose = close
+			s.quoteCollector = newQuoteCollectorPair(s, open, close, spaces)
+		}
+	}
+}
+
+// WithFuzzyWhitespace sets the characters that QuoteFuzzy skips around the quotes (default " \t").
+// As in bytes.Trim, cutset is a set of UTF-8 characters, so non-breaking spaces can be used.
+// It should be called after WithQuote (or WithQuotePair) and has no effect with QuoteStrict.
+func WithFuzzyWhitespace(cutset []byte) Option {
+	return func(s *scanner) {
+		switch c := s.quoteCollector.(type) {
+		case *quoteCollectorFuzzy:
+			c.spaces = string(cutset)
+		case *quoteCollectorPair:
+			if c.spaces != "" {
+				c.spaces = string(cutset)
+			}
+		}
+	}
+}
+
+// WithFuzzyTrimAfterCloseOnly changes what QuoteFuzzy does with the spaces after the closing quote.
+// By default the spaces around the quotes are skipped: `  "a"  ,b` is read as "a" and "b".
+// With this option, only the spaces before the opening quote are skipped,
+// and the spaces between the closing quote and the separator are kept at the end of the field:
+// `  "a"  ,b` is read as "a  " and "b".
+// It has no effect with QuoteStrict, that accepts no spaces around the quotes.
+func WithFuzzyTrimAfterCloseOnly() Option {
+	return func(s *scanner) {
+		s.keepAfterClose = true
+	}
+}
+
+// fuzzySpaces returns the characters skipped around the quotes by the quote collector ("" if none).
+func (s *scanner) fuzzySpaces() string {
+	switch c := s.quoteCollector.(type) {
+	case *quoteCollectorFuzzy:
+		return c.cutset
+	case *quoteCollectorPair:
+		return c.cutset
+	}
+	return ""
+}
+
+// setFuzzyCutset sets the characters skipped around the quotes by the quote collector,
+// once the spaces and the separator are known.
+func (s *scanner) setFuzzyCutset() {
+	switch c := s.quoteCollector.(type) {
+	case *quoteCollectorFuzzy:
+		c.cutset = fuzzyCutset(c.spaces, s.sep)
+	case *quoteCollectorPair:
+		c.cutset = fuzzyCutset(c.spaces, s.sep)
+	}
+}
+
+// WithEscape sets the escape character. It should be called after WithQuote, that sets the escape to the quote.
+// The three possible states are:
+//   - WithEscape(quote): the quotes are escaped by doubling them ("a""b" is a"b), the default;
+//   - WithEscape('\\') (or another character): the quotes are escaped by the escape character ("a\"b" is a"b);
+//   - WithEscape(0): no escaping, the first quote followed by a separator closes the field ("a""b" is a""b).
+//
+// With WithQuotePair, 0 means that the closing quote is escaped by doubling it.
 func WithEscape(escape byte) Option {
 	return func(s *scanner) {
 		s.escape = escape
@@ -213,32 +574,389 @@ func WithEscape(escape byte) Option {
 }
 
 // WithComment sets the comment prefix.
+// An empty prefix disables the comments.
+// The prefix is removed only once, so the text of "## literal" is "# literal".
+// The comment collector is created only if the comments were disabled,
+// so the options set by WithCommentIndent are kept when the prefix changes.
 func WithComment(comment []byte) Option {
+	return WithComments(comment)
+}
+
+// WithComments sets several comment prefixes (like "#" and "//").
+// At the row start the longest matching prefix is used, and it is returned by CommentPrefix().
+// The empty prefixes are ignored, and if no prefix is left, the comments are disabled.
+func WithComments(prefixes ...[]byte) Option {
 	return func(s *scanner) {
-		s.comment = comment
-		if len(comment) > 0 {
-			s.commentCollector = newCommentCollector(s)
-		} else {
+		s.comment = nil
+		s.comments = nil
+		for _, p := range prefixes {
+			if len(p) > 0 {
+				s.comments = append(s.comments, p)
+			}
+		}
+		switch {
+		case len(s.comments) == 0:
 			s.commentCollector = nil
+		case s.commentCollector == nil:
+			s.commentCollector = newCommentCollector(s)
+		}
+		if len(s.comments) > 0 {
+			s.comment = s.comments[0]
+		}
+	}
+}
+
+// WithCommentIndent allows (or not) spaces and tabs before the comment prefix at the row start,
+// so that indented lines are comments too. By default the prefix should be the first characters of the row.
+// It should be called after WithComment.
+func WithCommentIndent(indent bool) Option {
+	return func(s *scanner) {
+		if c, ok := s.commentCollector.(*commentCollector); ok {
+			c.indent = indent
 		}
 	}
 }
 
+// WithRawComments makes Bytes() return the comments as they are in the input,
+// with the comment prefix (and the spaces before it, see WithCommentIndent), like "# note" instead of " note".
+// It allows to write the comments back verbatim (like with the writer option WithRawComment and an empty prefix).
+// It should be called after WithComment.
+func WithRawComments() Option {
+	return func(s *scanner) {
+		if c, ok := s.commentCollector.(*commentCollector); ok {
+			c.raw = true
+		}
+	}
+}
+
+// commentText returns the text of the current comment without its prefix, even with WithRawComments.
+func (s *scanner) commentText() []byte {
+	if c, ok := s.commentCollector.(*commentCollector); ok && c.raw {
+		return bytes.TrimPrefix(bytes.TrimLeft(s.value, defaultFuzzySpaces), c.matched)
+	}
+	return s.value
+}
+
+// WithEscapedSeparators allows escaped separators in the unquoted fields,
+// so that a\,b is read as the single field "a,b" (if the escape character is '\\').
+// In the unquoted fields <esc><sep> is replaced by <sep> and <esc><esc> by <esc>.
+// The escape character (see WithEscape) should be different from the quote character.
+func WithEscapedSeparators() Option {
+	return func(s *scanner) {
+		s.sepCollector = &sepCollector{s}
+	}
+}
+
+// WithNormalizeLineEndings replaces "\r\n" and '\r' by '\n' in the fields,
+// so that no '\r' is returned by Bytes(), even inside quoted fields or for files with mixed line endings.
+// Without this option only the "\r\n" that terminate the rows are removed.
+func WithNormalizeLineEndings() Option {
+	return func(s *scanner) {
+		s.normalizeEOL = true
+	}
+}
+
+// WithSkipRepeatedHeader makes NextRow() skip the data rows equal (field by field) to the first data row (the header),
+// like the repeated headers of concatenated files. The header itself is returned.
+// Only NextRow() (and the functions using it, like writer.Transform) skip the rows, Scan() is not affected.
+func WithSkipRepeatedHeader() Option {
+	return func(s *scanner) {
+		s.skipHeader = true
+	}
+}
+
+// WithHeaderTransform sets a function applied to the fields of the header,
+// that is the first data row returned by NextRow() (and so by ReadInto()), like strings.ToLower or TrimHeaderName.
+// The other rows and the fields returned by Scan() are not transformed.
+// By default the header is not transformed, but TrimHeaderName is recommended if the header names are used as keys.
+func WithHeaderTransform(fn func(string) string) Option {
+	return func(s *scanner) {
+		s.headerFn = fn
+	}
+}
+
+// TrimHeaderName removes the UTF-8 BOM and the leading and trailing white spaces of a header name.
+// It could be used with WithHeaderTransform, so that the first key is "id" and not "\ufeffid".
+func TrimHeaderName(name string) string {
+	return strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+}
+
+// WithRaggedHandler sets a function that reshapes the data rows returned by NextRow() (and ReadInto())
+// that do not have the same number of fields as the first data row (the expected number).
+// The function receives the fields (valid only until the next row) and returns the normalized row,
+// for example by merging the extra fields into the last one.
+func WithRaggedHandler(fn func(fields [][]byte, expected int) [][]byte) Option {
+	return func(s *scanner) {
+		s.ragged = fn
+	}
+}
+
+// WithStopAtComment makes Scan() return false when it reaches a comment with the text marker
+// (without the comment prefix and the leading and trailing spaces), like "END" for "# END".
+// This comment is not returned and the rest of the input is not read, as at the end of the input (Err() is nil).
+// It allows to read only the data section of a file followed by other content.
+func WithStopAtComment(marker []byte) Option {
+	return func(s *scanner) {
+		s.stopMarker = append([]byte{}, marker...)
+	}
+}
+
+// WithChunkObserver sets a function called for each chunk read by Scan() from the input,
+// that is the data up to the next separator (included), and atRowEnd is true if this separator ends the row.
+// The last chunk of an input without a final line break ends with an added record separator.
+// It is a debugging tool, to see how the fields are split in chunks, and has no effect on the scanning.
+// The chunk is valid only during the call and should not be modified.
+func WithChunkObserver(fn func(chunk []byte, atRowEnd bool)) Option {
+	return func(s *scanner) {
+		s.chunkObserver = fn
+	}
+}
+
+// WithColumnCountHistogram makes the scanner count the data rows for each number of fields
+// (the comments and the empty lines are not counted), see ColumnCountHistogram().
+func WithColumnCountHistogram() Option {
+	return func(s *scanner) {
+		s.colsHist = make(map[int]int)
+	}
+}
+
+// WithIgnoreTrailingSeparator makes CurrentRow() (and so NextRow() and ReadInto()) ignore a separator at the end of the rows
+// (like "a,b,c,\n", as written by the writer option WithTrailingSeparator):
+// the last field of a row is dropped if it is empty and not quoted (and if it is not the only field).
+// Scan() still returns this empty field.
+func WithIgnoreTrailingSeparator() Option {
+	return func(s *scanner) {
+		s.ignoreTrailingSep = true
+	}
+}
+
+// WithIgnoreLeadingSeparator makes the scanner ignore a separator at the start of the rows (like ",a,b\n"):
+// the first field of a row is skipped if it is empty and not quoted (and if it is not the only field),
+// so the next field is the first field of the row (AtRowStart() is true), even if it starts with the comment prefix.
+// Only one separator is ignored: ",,a" is read as "" and "a", and a row "," is read as an empty line (a single empty field).
+// Unlike WithIgnoreTrailingSeparator, Scan() does not return the skipped field.
+func WithIgnoreLeadingSeparator() Option {
+	return func(s *scanner) {
+		s.ignoreLeadingSep = true
+	}
+}
+
+// WithSubSeparator sets the sub-separator of the lists packed in the fields of the given columns (like "a|b|c"),
+// that are split by ReadCellsInto(). The indices are those of the fields returned by ReadInto() (see WithColumns).
+// The sub-separator can not be escaped, and an empty field is an empty list.
+func WithSubSeparator(subsep byte, columns ...int) Option {
+	return func(s *scanner) {
+		s.subsep = subsep
+		s.subColumns = make(map[int]bool, len(columns))
+		for _, i := range columns {
+			s.subColumns[i] = true
+		}
+	}
+}
+
+// WithColumns makes ReadInto() return only the fields with the given indices (starting at 0), in the given order.
+// A missing field (the row is too short) is returned as an empty string.
+// The other fields are still scanned but are not converted to strings.
+func WithColumns(indices ...int) Option {
+	return func(s *scanner) {
+		s.columns = append([]int{}, indices...)
+	}
+}
+
+// WithInitialBufferSize sets the initial size of the buffer used to read the input by New
+// (the buffer grows when needed to hold a chunk up to the next separator, as in bufio.Scanner).
+// A larger initial buffer avoids the reallocations for inputs with long fields.
+// The maximal size is the larger of n and bufio.MaxScanTokenSize.
+// It has no effect on the scanners returned by NewBytes, and should be set before the first Scan().
+func WithInitialBufferSize(n int) Option {
+	return func(s *scanner) {
+		if src, ok := s.src.(*bufio.Scanner); ok && n > 0 {
+			src.Buffer(make([]byte, 0, n), max(n, bufio.MaxScanTokenSize))
+		}
+	}
+}
+
+// WithRowBuffering makes the scanner read the whole row on the first call to Scan() of the row,
+// so that RowColumns() is known from the first field. The fields are still delivered one by one by Scan().
+// This costs a copy of every field value in an internal buffer (that grows to the size of the largest row).
+func WithRowBuffering() Option {
+	return func(s *scanner) {
+		s.buffering = true
+	}
+}
+
+// WithUnsafeStrings makes ReadInto() return strings that alias the internal buffer, without allocations.
+// These strings are valid only until the next call to ReadInto() (or NextRow() or CurrentRow()),
+// and should be copied (with strings.Clone) to be retained.
+func WithUnsafeStrings() Option {
+	return func(s *scanner) {
+		s.unsafeStrings = true
+	}
+}
+
+// WithKeepEmptyLines makes ReadInto() (and ReadCellsInto()) return the empty lines as rows without fields (like []string{}),
+// instead of skipping them. A line with a single quoted empty field ("") is returned as a row with one empty field ([]string{""}).
+// As for IsEmptyLine(), a line with only spaces (or tabs, depending on the separator) is empty.
+func WithKeepEmptyLines() Option {
+	return func(s *scanner) {
+		s.keepEmpty = true
+	}
+}
+
+// utf8BOM is the UTF-8 byte order mark.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// WithSkipBOM skips (or not) the UTF-8 byte order mark at the start of the input.
+// By default (or if skip is false) the BOM is kept in the first field.
+// The offsets are always in the input: the skipped BOM is part of the first field (that starts at offset 0),
+// and QuoteSpans() counts its 3 bytes.
+func WithSkipBOM(skip bool) Option {
+	return func(s *scanner) {
+		s.skipBOM = skip
+	}
+}
+
+// WithExcelSepDirective makes the scanner honor a first line "sep=X" (as written by Excel),
+// where X is a single character: the separator is set to X and the line is skipped.
+// The offsets and the line numbers still count the skipped line.
+func WithExcelSepDirective() Option {
+	return func(s *scanner) {
+		s.excelSep = true
+	}
+}
+
+// maxSepDirective is the maximal length of the "sep=X\r\n" line.
+const maxSepDirective = 7
+
+// sepDirective returns the separator and the length of the "sep=X" line at the start of data,
+// or 0, 0 if data does not start with such line.
+// The line is terminated by "\n", "\r\n" or the end of data.
+func sepDirective(data []byte) (sep byte, n int) {
+	if len(data) < 5 || !bytes.HasPrefix(data, []byte("sep=")) {
+		return 0, 0
+	}
+	rest := data[5:]
+	switch {
+	case len(rest) == 0:
+		n = 5
+	case rest[0] == '\n':
+		n = 6
+	case len(rest) >= 2 && rest[0] == '\r' && rest[1] == '\n':
+		n = 7
+	default:
+		return 0, 0
+	}
+	sep = data[4]
+	if sep == '\n' || sep == '\r' {
+		return 0, 0
+	}
+	return sep, n
+}
+
+// skipSepDirective sets the separator if data starts with a "sep=X" line
+// and returns the length of this line (0 if none).
+func (s *scanner) skipSepDirective(data []byte) int {
+	sep, n := sepDirective(data)
+	if n == 0 {
+		return 0
+	}
+	s.Options(WithSeparator(sep))
+	// count the skipped line
+	s.offset = n
+	if data[n-1] == '\n' {
+		s.lines = 1
+	}
+	return n
+}
+
+// WithDecimalComma replaces the decimal comma by a dot in the unquoted numeric fields (like "-1,5"),
+// so they can be parsed by strconv.ParseFloat. It is useful for European data separated by ';'.
+// Only the fields made of an optional sign, digits, a single comma and digits are changed.
+func WithDecimalComma() Option {
+	return func(s *scanner) {
+		s.decimalComma = true
+	}
+}
+
+// WithStripNulls removes the NUL bytes ('\x00') from the fields.
+// This adds a scan of each field (only when this option is used).
+func WithStripNulls() Option {
+	return func(s *scanner) {
+		s.nulls = nullStrip
+	}
+}
+
+// WithRejectNulls stops the scan when a field contains a NUL byte ('\x00'),
+// in which case Err() returns an error wrapping ErrNull.
+// This adds a scan of each field (only when this option is used).
+func WithRejectNulls() Option {
+	return func(s *scanner) {
+		s.nulls = nullReject
+	}
+}
+
+// WithMaxColumns stops the scan when a row has more than n fields,
+// in which case Err() returns an error wrapping ErrTooManyColumns.
+// This protects the code that collects the rows against pathological inputs
+// (like a line with millions of separators). For untrusted (web-facing) input
+// a limit like 1024 is a reasonable choice. If n <= 0, there is no limit (default).
+func WithMaxColumns(n int) Option {
+	return func(s *scanner) {
+		s.maxColumns = n
+	}
+}
+
+// DefaultOptions are the options applied by New before the custom ones.
+// The options are applied in order, so the later options override the earlier ones.
+// Use Defaults to get a copy of these options with some overrides.
 var DefaultOptions = []Option{
 	WithSeparator(','),
 	WithQuote('"', QuoteFuzzy),
 	WithComment([]byte("#")),
 }
 
+// Defaults returns a new slice with the DefaultOptions followed by the overrides,
+// that could be passed to New or modified without changing DefaultOptions.
+func Defaults(overrides ...Option) []Option {
+	return append(append([]Option(nil), DefaultOptions...), overrides...)
+}
+
 // NewScanner returns a new Scanner to read from r.
 func New(r io.Reader, options ...Option) Scanner {
+	in := &inputReader{r}
+	s := newScanner(bufio.NewScanner(in), options...)
+	if s.autoUTF16 {
+		r = decodeUTF16(r)
+		in.Reader = r
+	}
+	if s.excelSep {
+		// peek the first line before the first Scan()
+		peek := make([]byte, maxSepDirective)
+		// a read error is not reported here, it is expected to happen again on the next read
+		n, _ := io.ReadFull(r, peek)
+		peek = peek[s.skipSepDirective(peek[:n]):n]
+		in.Reader = io.MultiReader(bytes.NewReader(peek), r)
+	}
+	return s
+}
+
+// inputReader is the reader of the bufio.Scanner used by New,
+// that allows to put back the bytes peeked before the first Scan().
+type inputReader struct {
+	io.Reader
+}
+
+// newScanner returns a new scanner reading the chunks from src.
+func newScanner(src source, options ...Option) *scanner {
 	s := &scanner{
-		// underlying bufio.Scanner
-		src: *bufio.NewScanner(r),
+		// underlying source scanner
+		src: src,
+		// default record separator
+		rs: '\n',
 		// initial state
 		// the first call to Scan() will switch AtRowStart to true and AtRowEnd to false
 		// because this is what happens after the last field of a row
-		atRowEnd: true,
+		fieldState: fieldState{atRowEnd: true},
 	}
 
 	// set default options
@@ -254,6 +972,17 @@ func (s *scanner) Options(options ...Option) {
 	for _, opt := range options {
 		opt(s)
 	}
+	s.setFuzzyCutset()
+	s.plain = s.isPlain()
+}
+
+// isPlain returns true if the fields are never quoted, commented or transformed,
+// in which case scanPlain could be used instead of the general scan.
+// This is the case of the plain files without quotes and comments (like WithQuote(0, nil) and WithComment(nil)).
+func (s *scanner) isPlain() bool {
+	return s.quoteCollector == nil && s.commentCollector == nil && s.sepCollector == nil &&
+		s.chunkObserver == nil && !s.skipBOM && s.nulls == nullKeep &&
+		!s.normalizeEOL && !s.decimalComma
 }
 
 // Separator returns the separator character
@@ -261,6 +990,11 @@ func (s *scanner) Separator() byte {
 	return s.sep
 }
 
+// RecordSeparator returns the record separator character
+func (s *scanner) RecordSeparator() byte {
+	return s.rs
+}
+
 // Quote returns the quote character
 func (s *scanner) Quote() byte {
 	return s.quote
@@ -276,40 +1010,195 @@ func (s *scanner) Comment() []byte {
 	return s.comment
 }
 
+// Comments returns the comment prefixes
+func (s *scanner) Comments() [][]byte {
+	return s.comments
+}
+
+func (s *scanner) ScanUntil(pred func(field []byte, atRowEnd bool) bool) bool {
+	return s.Scan() && !pred(s.Bytes(), s.AtRowEnd())
+}
+
 func (s *scanner) Scan() bool {
+	if !s.buffering {
+		return s.scan()
+	}
+	// read the whole row on its first field
+	if s.rowNext >= len(s.rowFields) {
+		if s.rowPending != nil {
+			// the buffering of the previous row was stopped by an error
+			s.err, s.rowPending = s.rowPending, nil
+			return false
+		}
+		if !s.bufferRow() {
+			return false
+		}
+	}
+	// deliver the next buffered field
+	f := s.rowFields[s.rowNext]
+	start := 0
+	if s.rowNext > 0 {
+		start = s.rowFields[s.rowNext-1].end
+	}
+	s.fieldState = f.state
+	s.value = s.rowValues[start:f.end:f.end]
+	if c, ok := s.commentCollector.(*commentCollector); ok {
+		c.matched = f.prefix
+	}
+	s.rowNext++
+	return true
+}
+
+// bufferRow scans all the fields of the next row and saves them in rowFields.
+// It returns false if no field was scanned.
+func (s *scanner) bufferRow() bool {
+	for {
+		s.rowFields = s.rowFields[:0]
+		s.rowValues = s.rowValues[:0]
+		s.rowNext = 0
+		s.rowErr = nil
+		var bad *ScanError // the first error of the row (see WithSkipBadRows)
+		rowCount := s.rowCount
+		for s.scan() {
+			if s.rowErr != nil && bad == nil {
+				bad = &ScanError{Line: s.line, Offset: s.offset, Err: s.rowErr}
+			}
+			s.rowValues = append(s.rowValues, s.value...)
+			f := bufferedField{state: s.fieldState, end: len(s.rowValues), prefix: s.CommentPrefix()}
+			s.rowFields = append(s.rowFields, f)
+			if s.atRowEnd {
+				break
+			}
+		}
+		if s.badRows != nil && recoverable(s.err) {
+			// the scan was stopped by an error of the row
+			if bad == nil {
+				bad = s.err.(*ScanError)
+			}
+			s.err = nil
+			s.skipRest()
+		}
+		if s.err != nil && len(s.rowFields) > 0 {
+			// deliver the scanned fields before the error
+			s.rowPending, s.err = s.err, nil
+		}
+		// all the fields of the row have the same number of columns
+		for i := range s.rowFields {
+			s.rowFields[i].state.cols = len(s.rowFields)
+		}
+		if s.badRows == nil || s.rowPending != nil || !s.skipRow(bad) {
+			return len(s.rowFields) > 0
+		}
+		s.uncountRow(rowCount)
+	}
+}
+
+// scan recovers the next field from the source,
+// skipping the empty field before a leading separator if WithIgnoreLeadingSeparator is used.
+func (s *scanner) scan() bool {
+	if !s.scanField() {
+		return false
+	}
+	if s.ignoreLeadingSep && s.atRowStart && !s.atRowEnd && !s.isQuoted && !s.isComment && len(s.value) == 0 {
+		return s.skipLeadingSep()
+	}
+	return true
+}
+
+// skipLeadingSep scans the field after the empty first field of the row (see WithIgnoreLeadingSeparator),
+// that becomes the first field of the row. A row made only of a separator (like ",\n") is read as an empty line,
+// at the end of the input too.
+func (s *scanner) skipLeadingSep() bool {
+	first := s.fieldState
+	// the next field starts the row, but it is not a comment
+	s.atRowEnd = true
+	s.afterLeadingSep = true
+	ok := s.scanField()
+	s.afterLeadingSep = false
+	if !ok && s.err == nil {
+		// the input ends with the separator: the empty first field is the whole row
+		s.fieldState = first
+		s.atRowEnd = true
+		return true
+	}
+	return ok
+}
+
+// scanField recovers the next field from the source, with scanPlain if possible.
+func (s *scanner) scanField() bool {
+	if s.plain {
+		return s.scanPlain()
+	}
+	// stop after an error or the stop marker
+	if s.err != nil || s.stopped {
+		return false
+	}
+	// the line of the field
+	s.line = s.lines + 1
 	// if we were at the end of the row, we are now at the start of the next row
 	s.atRowStart = s.atRowEnd
 	// add the length of the previous field to the offset
 	s.offset += s.rawlen
+	// count the fields of the row
+	if s.atRowStart {
+		s.cols = 0
+	}
+	s.cols++
+	if s.maxColumns > 0 && s.cols > s.maxColumns && !s.skipping {
+		s.rawlen = 0
+		s.err = &ScanError{Line: s.line, Offset: s.offset, Err: ErrTooManyColumns}
+		return false
+	}
 	// reset field values
 	// these values are set during the scan
-	s.value = s.value[:0]
+	s.value = s.buf[:0]
 	s.rawlen = 0
 	s.atRowEnd = false
 	s.isComment = false
 	s.isQuoted = false
+	s.openAt = -1
+	s.closeAt = -1
 	// start collecting data
 	var collector collector = nil
 	var start, stop bool // temporary variables for the collector
 	var ready bool       // ready to deliver the field ?
+	var aliased bool     // the field value is a slice of the input ?
 	for s.src.Scan() {
 		data := s.src.Bytes()
+		chunkStart, chunkLen := s.offset+s.rawlen, len(data)
 		s.rawlen += len(data)
 		// check if we are at the end of the line
 		// the chunk data is always terminated by a separator
-		s.atRowEnd = data[len(data)-1] == '\n'
+		s.atRowEnd = data[len(data)-1] == s.rs
+		if s.atRowEnd && !s.atEOF {
+			s.lines++
+		}
+		if s.chunkObserver != nil {
+			s.chunkObserver(data, s.atRowEnd)
+		}
 		// are we in the middle of a field?
 		if collector != nil {
 			// we are collecting data for a field
 			data, stop = collector.End(data)
 			s.value = append(s.value, data...)
+			// a bad quote ends the field (see WithSkipBadRows)
+			stop = s.badQuote(data) || stop
 			// do we need more data to end the field?
 			if !stop {
 				continue
 			}
+			if s.isQuoted {
+				// the closing quote follows the data
+				s.closeAt = chunkStart + len(data)
+			}
 		} else {
+			// skip the UTF-8 BOM at the start of the input
+			if s.skipBOM && chunkStart == 0 {
+				data = bytes.TrimPrefix(data, utf8BOM)
+			}
 			// check if we are starting a comment
-			if s.atRowStart && s.commentCollector != nil {
+			// (a quoted field wins if the comment prefix starts with the quote)
+			if s.atRowStart && !s.afterLeadingSep && s.commentCollector != nil && !s.startsWithQuote(data) {
 				data, start = s.commentCollector.Start(data)
 				if start {
 					// we are starting a comment and data is without the comment prefix
@@ -329,18 +1218,39 @@ func (s *scanner) Scan() bool {
 				if start {
 					// we are starting a quoted field
 					s.isQuoted = true
+					dataAt := chunkStart + chunkLen - len(data)
+					s.openAt = dataAt - s.openQuoteLen()
 					data, stop = s.quoteCollector.End(data)
 					s.value = append(s.value, data...)
+					stop = s.badQuote(data) || stop
 					// do we need more data to end the quoted field?
 					if !stop {
 						collector = s.quoteCollector
 						continue
 					}
+					// the closing quote follows the data
+					s.closeAt = dataAt + len(data)
 				}
 			}
 			// normal field
 			if !s.isComment && !s.isQuoted {
-				s.value = append(s.value, removeSeparator(data)...)
+				// check if the separator is escaped
+				if s.sepCollector != nil {
+					data, start = s.sepCollector.Start(data)
+					if start {
+						s.value = append(s.value, data...)
+						collector = s.sepCollector
+						continue
+					}
+				}
+				if s.zeroCopy && s.nulls != nullStrip {
+					v := removeSeparator(data)
+					s.value = v[:len(v):len(v)]
+					aliased = true
+				} else {
+					s.value = append(s.value, removeSeparator(data)...)
+				}
+				s.badQuote(s.value)
 			}
 		}
 		// end collecting data
@@ -354,34 +1264,434 @@ func (s *scanner) Scan() bool {
 	if !ready && collector != nil {
 		// we were collecting a field but the end of the file was reached
 		// this could be a comment without a line break at the end of the file or
-		// a quoted field without a closing quote (we hides this error)
+		// a quoted field without a closing quote (we hides this error, except with WithSkipBadRows)
 		s.atRowEnd = true
+		s.truncated = true
 		ready = true
+		if s.isQuoted && s.badRows != nil && s.rowErr == nil {
+			s.rowErr = ErrUnterminatedQuote
+		}
 	}
 	if !ready {
 		// no more data to deliver
+		// (the row of the previous field is not terminated if it ends with a separator)
+		s.truncated = s.truncated || !s.atRowStart
 		return false
 	}
+	if s.atRowEnd && s.atEOF {
+		// the last row has no line break
+		s.truncated = true
+	}
+	if s.keepAfterClose && s.isQuoted && s.closeAt != -1 {
+		// keep the spaces between the closing quote and the separator (in the last chunk)
+		raw := removeSeparator(s.src.Bytes())
+		s.value = append(s.value, raw[len(bytes.TrimRight(raw, s.fuzzySpaces())):]...)
+	}
+	if !aliased {
+		// keep the (eventually grown) buffer for the next fields
+		s.buf = s.value
+	}
 	// do we need to unescape quotes?
 	if s.isQuoted {
 		s.unescapeQuotes()
 	}
+	// do we need to handle NUL bytes?
+	if s.nulls != nullKeep && bytes.IndexByte(s.value, 0) >= 0 {
+		if s.nulls == nullReject && !s.skipping {
+			s.err = &ScanError{Line: s.line, Offset: s.offset, Err: ErrNull}
+			return false
+		}
+		s.stripNulls()
+	}
+	// do we need to unescape the separators?
+	if s.sepCollector != nil && !s.isQuoted && !s.isComment && s.escape != 0 && bytes.IndexByte(s.value, s.escape) >= 0 {
+		if aliased {
+			// do not modify the input
+			s.value = append(s.buf[:0], s.value...)
+			s.buf = s.value
+			aliased = false
+		}
+		s.unescapeSeparators()
+	}
+	// do we need to normalize the line endings?
+	if s.normalizeEOL && bytes.IndexByte(s.value, '\r') >= 0 {
+		if aliased {
+			// do not modify the input
+			s.value = append(s.buf[:0], s.value...)
+			s.buf = s.value
+			aliased = false
+		}
+		s.normalizeLineEndings()
+	}
+	// do we need to replace the decimal comma?
+	if s.decimalComma && !s.isQuoted && !s.isComment {
+		if i := decimalCommaIndex(s.value); i >= 0 {
+			if aliased {
+				// do not modify the input
+				s.value = append(s.buf[:0], s.value...)
+				s.buf = s.value
+			}
+			s.value[i] = '.'
+		}
+	}
+	// do we need to stop at this comment?
+	if s.isComment && s.stopMarker != nil && bytes.Equal(bytes.TrimSpace(s.commentText()), s.stopMarker) {
+		s.stopped = true
+		return false
+	}
+	// count the completed data rows
+	if s.atRowEnd && !s.isComment && !s.IsEmptyLine() && !s.skipping {
+		s.rowCount++
+		if s.colsHist != nil {
+			s.colsHist[s.cols]++
+		}
+	}
 	// we have a field
 	return true
 }
 
+// scanPlain is scan without the collectors and the field transformations (see isPlain):
+// each chunk of the source is a field.
+func (s *scanner) scanPlain() bool {
+	if s.err != nil {
+		return false
+	}
+	s.line = s.lines + 1
+	s.atRowStart = s.atRowEnd
+	s.offset += s.rawlen
+	if s.atRowStart {
+		s.cols = 0
+	}
+	s.cols++
+	s.rawlen = 0
+	if s.maxColumns > 0 && s.cols > s.maxColumns && !s.skipping {
+		s.err = &ScanError{Line: s.line, Offset: s.offset, Err: ErrTooManyColumns}
+		return false
+	}
+	if !s.src.Scan() {
+		// no more data to deliver (the error of the source, if any, is returned by Err)
+		if s.src.Err() == nil {
+			s.truncated = s.truncated || !s.atRowStart
+		}
+		return false
+	}
+	data := s.src.Bytes()
+	s.rawlen = len(data)
+	s.atRowEnd = data[len(data)-1] == s.rs
+	if s.atRowEnd {
+		if s.atEOF {
+			// the last row has no line break
+			s.truncated = true
+		} else {
+			s.lines++
+		}
+	}
+	s.openAt, s.closeAt = -1, -1
+	if s.zeroCopy {
+		v := removeSeparator(data)
+		s.value = v[:len(v):len(v)]
+	} else {
+		s.value = append(s.buf[:0], removeSeparator(data)...)
+		s.buf = s.value
+	}
+	// count the completed data rows
+	if s.atRowEnd && !s.IsEmptyLine() && !s.skipping {
+		s.rowCount++
+		if s.colsHist != nil {
+			s.colsHist[s.cols]++
+		}
+	}
+	return true
+}
+
+// unescapeSeparators transforms <esc><sep> to <sep> and <esc><esc> to <esc> in the current field s.value.
+func (s *scanner) unescapeSeparators() {
+	n := 0
+	for i := 0; i < len(s.value); i++ {
+		c := s.value[i]
+		if c == s.escape && i+1 < len(s.value) && (s.value[i+1] == s.sep || s.value[i+1] == s.escape) {
+			i++
+			c = s.value[i]
+		}
+		s.value[n] = c
+		n++
+	}
+	s.value = s.value[:n]
+}
+
+// normalizeLineEndings replaces "\r\n" and the remaining '\r' by '\n' in the current field s.value.
+func (s *scanner) normalizeLineEndings() {
+	n := 0
+	for i, c := range s.value {
+		if c == '\r' {
+			if i+1 < len(s.value) && s.value[i+1] == '\n' {
+				continue
+			}
+			c = '\n'
+		}
+		s.value[n] = c
+		n++
+	}
+	s.value = s.value[:n]
+}
+
+// decimalCommaIndex returns the index of the comma if data is a decimal number
+// with a comma as decimal mark (like "-1,5"), and -1 otherwise.
+func decimalCommaIndex(data []byte) int {
+	sign := 0
+	if len(data) > 0 && (data[0] == '-' || data[0] == '+') {
+		sign = 1
+	}
+	i := bytes.IndexByte(data, ',')
+	if i < sign+1 || i == len(data)-1 {
+		return -1
+	}
+	for j, c := range data[sign:] {
+		if j+sign != i && (c < '0' || c > '9') {
+			return -1
+		}
+	}
+	return i
+}
+
+// stripNulls removes the NUL bytes from the current field s.value.
+func (s *scanner) stripNulls() {
+	n := 0
+	for _, c := range s.value {
+		if c != 0 {
+			s.value[n] = c
+			n++
+		}
+	}
+	s.value = s.value[:n]
+}
+
+// startsWithQuote returns true if the first character of data that is not a space or a tab
+// starts the opening quote.
+func (s *scanner) startsWithQuote(data []byte) bool {
+	if s.quoteCollector == nil {
+		return false
+	}
+	data = data[skipSpaces(data, defaultFuzzySpaces):]
+	if s.quoteOpen != nil {
+		return bytes.HasPrefix(data, s.quoteOpen)
+	}
+	return len(data) > 0 && data[0] == s.quote
+}
+
+// openQuoteLen returns the length of the opening quote.
+func (s *scanner) openQuoteLen() int {
+	if s.quoteOpen != nil {
+		return len(s.quoteOpen)
+	}
+	return 1
+}
+
 func (s *scanner) Err() error {
-	return s.src.Err()
+	if s.err == nil && s.src.Err() != nil {
+		offset := s.offset + s.rawlen
+		if s.atEOF {
+			// do not count the line end added to the last chunk
+			offset--
+		}
+		s.err = &ScanError{Line: s.lines + 1, Offset: offset, Err: s.src.Err()}
+	}
+	if len(s.badErrs) > 0 {
+		// the errors of the skipped rows (see WithSkipBadRows)
+		errs := append(MultiError{}, s.badErrs...)
+		if s.err != nil {
+			errs = append(errs, s.err)
+		}
+		return errs
+	}
+	return s.err
 }
 
 func (s *scanner) Bytes() []byte {
 	return s.value
 }
 
+func (s *scanner) CurrentRow() [][]byte {
+	s.rowBuf = append(s.rowBuf[:0], s.value...)
+	s.rowEnds = append(s.rowEnds[:0], len(s.rowBuf))
+	for !s.atRowEnd && s.Scan() {
+		s.rowBuf = append(s.rowBuf, s.value...)
+		s.rowEnds = append(s.rowEnds, len(s.rowBuf))
+	}
+	// drop the empty field after a trailing separator
+	if s.ignoreTrailingSep && len(s.rowEnds) > 1 && !s.isQuoted && !s.isComment && len(s.value) == 0 {
+		s.rowEnds = s.rowEnds[:len(s.rowEnds)-1]
+	}
+	// slice the buffer only now, because it could be reallocated during the loop
+	s.row = s.row[:0]
+	start := 0
+	for _, end := range s.rowEnds {
+		s.row = append(s.row, s.rowBuf[start:end:end])
+		start = end
+	}
+	return s.row
+}
+
+func (s *scanner) NextRow() (Row, bool) {
+	for s.Scan() {
+		r := Row{
+			line:    s.line,
+			comment: s.IsComment(),
+			empty:   s.IsEmptyLine(),
+			quoted:  s.IsQuoted(),
+		}
+		r.fields = s.CurrentRow()
+		if s.skipHeader && !r.comment && !r.empty {
+			if s.header == nil {
+				for _, f := range r.fields {
+					s.header = append(s.header, append([]byte{}, f...))
+				}
+			} else if equalFields(r.fields, s.header) {
+				continue
+			}
+		}
+		if s.headerFn != nil && !s.headerDone && !r.comment && !r.empty {
+			s.headerRow = s.headerRow[:0]
+			for _, f := range r.fields {
+				s.headerRow = append(s.headerRow, []byte(s.headerFn(string(f))))
+			}
+			r.fields = s.headerRow
+		}
+		if !r.comment && !r.empty {
+			s.headerDone = true
+		}
+		if s.ragged != nil && !r.comment && !r.empty {
+			if s.expectedCols == 0 {
+				s.expectedCols = len(r.fields)
+			} else if len(r.fields) != s.expectedCols {
+				r.fields = s.ragged(r.fields, s.expectedCols)
+			}
+		}
+		return r, true
+	}
+	return Row{}, false
+}
+
+// ReadInto reads the next data row into dst, reusing the slice (that grows if needed).
+// The comments and the empty lines are skipped (see WithKeepEmptyLines).
+// A line break terminates the row before it, so a single line break at the end of the input is not an empty line,
+// but a second one (like in "a\n\n"), or a last line with only spaces (like in "a\n  "), is.
+// The strings are new allocations (safe to retain), except if WithUnsafeStrings() is used.
+// At the end of the input, Err() is returned if not nil, else io.EOF.
+func (s *scanner) ReadInto(dst *[]string) error {
+	for {
+		row, ok := s.NextRow()
+		if !ok {
+			if err := s.Err(); err != nil {
+				return err
+			}
+			return io.EOF
+		}
+		if row.comment || (row.empty && !s.keepEmpty) {
+			continue
+		}
+		*dst = (*dst)[:0]
+		if row.empty && !row.quoted {
+			// a blank line has no fields
+			return nil
+		}
+		if s.columns == nil {
+			for _, f := range row.fields {
+				*dst = append(*dst, s.toString(f))
+			}
+			return nil
+		}
+		for _, i := range s.columns {
+			var f []byte
+			if i >= 0 && i < len(row.fields) {
+				f = row.fields[i]
+			}
+			*dst = append(*dst, s.toString(f))
+		}
+		return nil
+	}
+}
+
+// ReadCellsInto reads the next data row into dst, reusing its memory, as ReadInto.
+// The fields of the columns set by WithSubSeparator() are split (see SplitField),
+// and the other fields are cells with a single value.
+func (s *scanner) ReadCellsInto(dst *[][]string) error {
+	if err := s.ReadInto(&s.cellRow); err != nil {
+		return err
+	}
+	cells := (*dst)[:0]
+	for i, f := range s.cellRow {
+		var cell []string
+		if i < cap(cells) {
+			cell = cells[:i+1][i][:0]
+		}
+		if s.subColumns[i] {
+			if f != "" {
+				cell = append(cell, strings.Split(f, string(s.subsep))...)
+			}
+		} else {
+			cell = append(cell, f)
+		}
+		cells = append(cells, cell)
+	}
+	*dst = cells
+	return nil
+}
+
+// toString returns the field as a string, that aliases it if WithUnsafeStrings() is used.
+func (s *scanner) toString(f []byte) string {
+	if s.unsafeStrings {
+		return unsafe.String(unsafe.SliceData(f), len(f))
+	}
+	return string(f)
+}
+
+// equalFields returns true if a and b have the same fields.
+func equalFields(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *scanner) Offset() int {
 	return s.offset
 }
 
+func (s *scanner) RowColumns() int {
+	if s.buffering || s.atRowEnd {
+		return s.cols
+	}
+	return -1
+}
+
+func (s *scanner) RowCount() int {
+	return s.rowCount
+}
+
+func (s *scanner) PhysicalLine() int {
+	return s.line
+}
+
+func (s *scanner) RecordNumber() int {
+	if s.isComment || s.IsEmptyLine() {
+		return 0
+	}
+	if s.atRowEnd {
+		return s.rowCount
+	}
+	return s.rowCount + 1
+}
+
+func (s *scanner) ColumnCountHistogram() map[int]int {
+	return s.colsHist
+}
+
 func (s *scanner) AtRowStart() bool {
 	return s.atRowStart
 }
@@ -390,14 +1700,36 @@ func (s *scanner) AtRowEnd() bool {
 	return s.atRowEnd
 }
 
+func (s *scanner) EndedWithNewline() bool {
+	return s.atRowEnd && !s.atEOF
+}
+
+func (s *scanner) Truncated() bool {
+	return s.truncated
+}
+
 func (s *scanner) IsComment() bool {
 	return s.isComment
 }
 
+func (s *scanner) CommentPrefix() []byte {
+	if !s.isComment {
+		return nil
+	}
+	if c, ok := s.commentCollector.(*commentCollector); ok {
+		return c.matched
+	}
+	return s.comment
+}
+
 func (s *scanner) IsQuoted() bool {
 	return s.isQuoted
 }
 
+func (s *scanner) QuoteSpans() (openAt, closeAt int) {
+	return s.openAt, s.closeAt
+}
+
 func (s *scanner) IsEmptyLine() bool {
 	return s.AtRowStart() && s.AtRowEnd() && s.empty(s.Bytes())
 }
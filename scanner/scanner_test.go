@@ -1,8 +1,14 @@
 package scanner
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 // TestRemoveQuotes tests the removeQuotes transformer.
@@ -49,3 +55,1185 @@ func TestUnescapeQuotes(t *testing.T) {
 		}
 	}
 }
+
+// scanFields returns all the fields of data scanned with the given options.
+func scanFields(data string, options ...Option) []string {
+	sc := New(strings.NewReader(data), options...)
+	fields := []string{}
+	for sc.Scan() {
+		fields = append(fields, string(sc.Bytes()))
+	}
+	return fields
+}
+
+func TestQuotePair(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"“a,b”,c\n", []Option{WithQuotePair([]byte("“"), []byte("”"))}, []string{"a,b", "c"}},
+		{"“a””b”,“c“d”\n", []Option{WithQuotePair([]byte("“"), []byte("”"))}, []string{"a”b", "c“d"}},
+		{" “a” ,“b\n”\n", []Option{WithQuotePair([]byte("“"), []byte("”"))}, []string{"a", "b\n"}},
+		{" “a” ,b\n", []Option{WithQuote('"', QuoteStrict), WithQuotePair([]byte("“"), []byte("”"))}, []string{" “a” ", "b"}},
+		{"“a\\”b”,c\n", []Option{WithQuotePair([]byte("“"), []byte("”")), WithEscape('\\')}, []string{"a”b", "c"}},
+		{"«a»,“b”\n", []Option{WithQuotePair([]byte("«"), []byte("»"))}, []string{"a", "“b”"}},
+		{"\"a\",b\n", []Option{WithQuotePair([]byte("«"), []byte("»"))}, []string{"\"a\"", "b"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%s> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestEndedWithNewline(t *testing.T) {
+	data := []struct {
+		in       string
+		expected []bool
+	}{
+		{"a,b\nc,d\n", []bool{false, true, false, true}},
+		{"a,b\nc,d", []bool{false, true, false, false}},
+		{"a,b\r\nc\r\n", []bool{false, true, true}},
+		{"a,\"b\nc\"", []bool{false, false}},
+		{"a,\"b\nc", []bool{false, false}},
+		{"# comment", []bool{false}},
+		{"# comment\n\n", []bool{true, true}},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in))
+		got := []bool{}
+		for sc.Scan() {
+			got = append(got, sc.EndedWithNewline())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(d.expected) {
+			t.Errorf("for <%q> expected %v, got %v", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected bool
+	}{
+		{"", nil, false},
+		{"a,b\nc,d\n", nil, false},
+		{"a,b\nc,d", nil, true},
+		{"a,b\nc,", nil, true},
+		{"a,\"b\nc\"\n", nil, false},
+		{"a,\"b\nc\"", nil, true},
+		{"a,\"b\nc\n", nil, true},
+		{"# comment\n", nil, false},
+		{"# comment", nil, true},
+		{"a\x1fb\x1e", []Option{WithSeparator(0x1f), WithRecordSeparator(0x1e)}, false},
+		{"a\x1fb", []Option{WithSeparator(0x1f), WithRecordSeparator(0x1e)}, true},
+		{"a\n#end\nb", []Option{WithStopAtComment([]byte("end"))}, false},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in), d.options...)
+		for sc.Scan() {
+		}
+		if got := sc.Truncated(); got != d.expected {
+			t.Errorf("for <%q> expected %v, got %v", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestRecordSeparator(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"a\x1fb\x1ec\x1fd\x1e", []Option{WithSeparator(0x1f), WithRecordSeparator(0x1e)}, []string{"a", "b", "c", "d"}},
+		{"a\nb,c;d,e", []Option{WithRecordSeparator(';')}, []string{"a\nb", "c", "d", "e"}},
+		{"a\r\n;b\n", []Option{WithRecordSeparator(';')}, []string{"a\r\n", "b\n"}},
+		{"#x,y;a,b", []Option{WithRecordSeparator(';')}, []string{"x,y", "a", "b"}},
+		{"a;b;", []Option{WithSeparator(';'), WithRecordSeparator(';')}, []string{"a", "b"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+	// row ends
+	sc := New(strings.NewReader("a,b|c|"), WithRecordSeparator('|'))
+	got := []bool{}
+	for sc.Scan() {
+		got = append(got, sc.AtRowEnd())
+	}
+	if fmt.Sprint(got) != "[false true true]" {
+		t.Errorf("expected row ends [false true true], got %v", got)
+	}
+}
+
+func TestIgnoreLeadingSeparator(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected string
+	}{
+		{",a,b\nc,d\n", nil, `["" "a" "b"]|["c" "d"]`},
+		{",a,b\nc,d\n", []Option{WithIgnoreLeadingSeparator()}, `["a" "b"]|["c" "d"]`},
+		{",,a\n,b,\n", []Option{WithIgnoreLeadingSeparator()}, `["" "a"]|["b" ""]`},
+		{"\"\",a\n \n#,c\n,\"d\ne\"", []Option{WithIgnoreLeadingSeparator()}, `["" "a"]|[" "]|[",c"]|["d\ne"]`},
+		{";a;b\n", []Option{WithSeparator(';'), WithQuote(0, nil), WithComment(nil), WithIgnoreLeadingSeparator()}, `["a" "b"]`},
+		{",#x,b\n#y\n", []Option{WithIgnoreLeadingSeparator()}, `["#x" "b"]|["y"]`},
+		{"a\n,\n,", []Option{WithIgnoreLeadingSeparator()}, `["a"]|[""]|[""]`},
+		{",\r\n,\n", []Option{WithIgnoreLeadingSeparator()}, `[""]|[""]`},
+		{",\n,", []Option{WithSeparator(','), WithQuote(0, nil), WithComment(nil), WithIgnoreLeadingSeparator()}, `[""]|[""]`},
+	}
+	for _, d := range data {
+		for _, buffering := range []bool{false, true} {
+			options := d.options
+			if buffering {
+				options = append(options[:len(options):len(options)], WithRowBuffering())
+			}
+			sc := New(strings.NewReader(d.in), options...)
+			got := []string{}
+			for sc.Scan() {
+				if !sc.AtRowStart() {
+					t.Fatalf("for <%q> expected a row start", d.in)
+				}
+				got = append(got, fmt.Sprintf("%q", sc.CurrentRow()))
+			}
+			if strings.Join(got, "|") != d.expected {
+				t.Errorf("for <%q> (buffering %v) expected %s, got %s", d.in, buffering, d.expected, strings.Join(got, "|"))
+			}
+		}
+	}
+	// the row and column counts
+	// (the rows made of a separator are empty lines)
+	sc := New(strings.NewReader(",a,b\n,\n,"), WithIgnoreLeadingSeparator(), WithColumnCountHistogram())
+	for sc.Scan() {
+	}
+	if got := fmt.Sprint(sc.ColumnCountHistogram(), sc.RowCount()); got != "map[2:1] 1" {
+		t.Errorf("expected map[2:1] 1, got %s", got)
+	}
+}
+
+func TestRecordSeparatorInQuotes(t *testing.T) {
+	ascii := []Option{WithSeparator(0x1f), WithRecordSeparator(0x1e)}
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"a\x1f\"b\x1ec\"\x1ed\x1e", append(ascii, WithQuote('"', QuoteStrict)), []string{"a", "b\x1ec", "d"}},
+		{"a\x1f \"b\x1ec\" \x1ed\x1e", append(ascii, WithQuote('"', QuoteFuzzy)), []string{"a", "b\x1ec", "d"}},
+		{"\"\x1e\"\x1f\"\x1e\x1e\"\x1e", append(ascii, WithQuote('"', QuoteStrict)), []string{"\x1e", "\x1e\x1e"}},
+		{"a\x1f<b\x1ec>\x1ed", append(ascii, WithQuotePair([]byte("<"), []byte(">"))), []string{"a", "b\x1ec", "d"}},
+		{"\"a;b\";\"c\nd\"", []Option{WithRecordSeparator(';'), WithQuote('"', QuoteStrict)}, []string{"a;b", "c\nd"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+		// the same without copy
+		got = got[:0]
+		sc := NewBytes([]byte(d.in), d.options...)
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> with NewBytes expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+	// the record separator inside the quotes does not end the row
+	sc := New(strings.NewReader("\"a\x1eb\"\x1fc\x1ed\x1e"), append(ascii, WithQuote('"', QuoteStrict))...)
+	got := []bool{}
+	for sc.Scan() {
+		got = append(got, sc.AtRowEnd())
+	}
+	if fmt.Sprint(got) != "[false true true]" {
+		t.Errorf("expected row ends [false true true], got %v", got)
+	}
+}
+
+func TestFuzzyTrimAfterCloseOnly(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{` "a"  ,b,  "c" `, nil, []string{"a", "b", "c"}},
+		{` "a"  ,b,  "c" `, []Option{WithFuzzyTrimAfterCloseOnly()}, []string{"a  ", "b", "c "}},
+		{"\"a\nb\"\t; c\r\n", []Option{WithSeparator(';'), WithFuzzyTrimAfterCloseOnly()}, []string{"a\nb\t", " c"}},
+		{"\"a\",\"b\"", []Option{WithQuote('"', QuoteStrict), WithFuzzyTrimAfterCloseOnly()}, []string{"a", "b"}},
+		{" <a> ,b", []Option{WithQuotePair([]byte("<"), []byte(">")), WithFuzzyTrimAfterCloseOnly()}, []string{"a ", "b"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestFuzzyWhitespace(t *testing.T) {
+	nbsp := "\u00a0"
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{" \t\"a\" \t,b\n", nil, []string{"a", "b"}},
+		{"\r\"a\"\r,b\n", nil, []string{"\r\"a\"\r", "b"}},
+		{"\r\"a\"\r,b\n", []Option{WithFuzzyWhitespace([]byte(" \t\r"))}, []string{"a", "b"}},
+		{nbsp + "\"a\"" + nbsp + ",b\n", []Option{WithFuzzyWhitespace([]byte(" " + nbsp))}, []string{"a", "b"}},
+		{"\"à\"" + nbsp + ",b\n", []Option{WithFuzzyWhitespace([]byte(nbsp))}, []string{"à", "b"}},
+		{"\t\"a\"\t,b\n", []Option{WithFuzzyWhitespace([]byte(" "))}, []string{"\t\"a\"\t", "b"}},
+		{" \"a\" ,b\n", []Option{WithQuote('"', QuoteStrict), WithFuzzyWhitespace([]byte(" "))}, []string{" \"a\" ", "b"}},
+		{"\r“a”\r,b\n", []Option{WithFuzzyWhitespace([]byte("\r")), WithQuotePair([]byte("“"), []byte("”"))}, []string{"a", "b"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestQuoteSpans(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected [][2]int
+	}{
+		{"a,b\n", nil, [][2]int{{-1, -1}, {-1, -1}}},
+		{"\"a\",b\n", nil, [][2]int{{0, 2}, {-1, -1}}},
+		{"x, \"a,b\" ,\"\"\n", nil, [][2]int{{-1, -1}, {3, 7}, {10, 11}}},
+		{"\"a\nb\"\"c\nd\"\n", nil, [][2]int{{0, 9}}},
+		{"a,\"b", nil, [][2]int{{-1, -1}, {2, -1}}},
+		{"# \"c\"\n\"a\"\n", nil, [][2]int{{-1, -1}, {6, 8}}},
+		{"x,“a”\n", []Option{WithQuotePair([]byte("“"), []byte("”"))}, [][2]int{{-1, -1}, {2, 6}}},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in), d.options...)
+		got := [][2]int{}
+		for sc.Scan() {
+			openAt, closeAt := sc.QuoteSpans()
+			got = append(got, [2]int{openAt, closeAt})
+		}
+		if fmt.Sprint(got) != fmt.Sprint(d.expected) {
+			t.Errorf("for <%q> expected %v, got %v", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestScanError(t *testing.T) {
+	boom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader("a,b\n\"c\nd\",e\nf"), iotest.ErrReader(boom))
+	sc := New(r)
+	for sc.Scan() {
+	}
+	err := sc.Err()
+	var serr *ScanError
+	if !errors.As(err, &serr) || !errors.Is(err, boom) {
+		t.Fatalf("expected a ScanError wrapping %v, got %v", boom, err)
+	}
+	if serr.Line != 4 || serr.Offset != 13 {
+		t.Errorf("expected error at line 4 offset 13, got %v", err)
+	}
+	// no error
+	sc = New(strings.NewReader("a,b"))
+	for sc.Scan() {
+	}
+	if err := sc.Err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestCurrentRow(t *testing.T) {
+	sc := New(strings.NewReader("# comment\na,\"b,c\",d\n\ne,f\ng"))
+	got := []string{}
+	for sc.Scan() {
+		if sc.AtRowStart() && !sc.IsComment() && !sc.IsEmptyLine() {
+			got = append(got, fmt.Sprintf("%q", sc.CurrentRow()))
+		}
+		if !sc.AtRowEnd() {
+			t.Errorf("expected to be at row end after CurrentRow()")
+		}
+	}
+	expected := []string{`["a" "b,c" "d"]`, `["e" "f"]`, `["g"]`}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestNulls(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+		err      bool
+	}{
+		{"a\x00b,c\n", nil, []string{"a\x00b", "c"}, false},
+		{"a\x00b,\"\x00c\x00\"\n\x00", []Option{WithStripNulls()}, []string{"ab", "c", ""}, false},
+		{"a,b\nc\x00,d\n", []Option{WithRejectNulls()}, []string{"a", "b"}, true},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in), d.options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		err := sc.Err()
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") || (err != nil) != d.err {
+			t.Errorf("for <%q> expected %q (error: %v), got %q (error: %v)", d.in, d.expected, d.err, got, err)
+		}
+		if d.err && (!errors.Is(err, ErrNull) || err.Error() != "line 2, offset 4: NUL byte in field") {
+			t.Errorf("for <%q> expected ErrNull at line 2, got %v", d.in, err)
+		}
+	}
+}
+
+func TestNextRow(t *testing.T) {
+	sc := New(strings.NewReader("# comment\na,\"b\nc\",d\n\ne,f"))
+	got := []string{}
+	for {
+		row, ok := sc.NextRow()
+		if !ok {
+			break
+		}
+		got = append(got, fmt.Sprintf("%d %t %t %q", row.Line(), row.IsComment(), row.IsEmpty(), row.Fields()))
+	}
+	expected := []string{
+		`1 true false [" comment"]`,
+		`2 false false ["a" "b\nc" "d"]`,
+		`4 false true [""]`,
+		`5 false false ["e" "f"]`,
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestEscapesAtChunkBoundaries(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"\"a,\"\",b\",c\n", nil, []string{"a,\",b", "c"}},
+		{"\"\"\"\",\"\",\"\"\"\"\"\",x\n", nil, []string{"\"", "", "\"\"", "x"}},
+		{"\",\"\",\",\"\"\n", nil, []string{",\",", ""}},
+		{"\"a,\\\",b\",c\n", []Option{WithEscape('\\')}, []string{"a,\",b", "c"}},
+		{"\"a\\\\\",b\n", []Option{WithEscape('\\')}, []string{"a\\\\", "b"}},
+		{"\"\\\",\\\"\",b\n", []Option{WithEscape('\\')}, []string{"\",\"", "b"}},
+	}
+	for _, d := range data {
+		for _, r := range []io.Reader{strings.NewReader(d.in), iotest.OneByteReader(strings.NewReader(d.in))} {
+			sc := New(r, d.options...)
+			got := []string{}
+			for sc.Scan() {
+				got = append(got, string(sc.Bytes()))
+			}
+			if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+				t.Errorf("for <%s> expected %q, got %q", d.in, d.expected, got)
+			}
+		}
+	}
+}
+
+func TestStrictCRLF(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"\"a\",\"b\"\r\n\"c\"\r\n", nil, []string{"a", "b", "c"}},
+		{"\"a\r\nb\"\r\n", nil, []string{"a\r\nb"}},
+		{"\"a\"\r", nil, []string{"a"}},
+		{"“a”\r\n", []Option{WithQuotePair([]byte("“"), []byte("”"))}, []string{"a"}},
+	}
+	for _, d := range data {
+		options := append([]Option{WithQuote('"', QuoteStrict)}, d.options...)
+		got := scanFields(d.in, options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestCommentIndent(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"  # a\nb,c\n", nil, []string{"  # a", "b", "c"}},
+		{"  # a\nb,c\n", []Option{WithCommentIndent(true)}, []string{" a", "b", "c"}},
+		{"\t//a\nb, //c\n", []Option{WithComment([]byte("//")), WithCommentIndent(true)}, []string{"a", "b", " //c"}},
+		{"  # a\n", []Option{WithCommentIndent(true), WithCommentIndent(false)}, []string{"  # a"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestMaxColumns(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+		err      bool
+	}{
+		{"a,b\nc,d\n", []Option{WithMaxColumns(2)}, []string{"a", "b", "c", "d"}, false},
+		{"a,b,c\n", []Option{WithMaxColumns(0)}, []string{"a", "b", "c"}, false},
+		{"a,b\nc,d,e\n", []Option{WithMaxColumns(2)}, []string{"a", "b", "c", "d"}, true},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in), d.options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		err := sc.Err()
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") || (err != nil) != d.err {
+			t.Errorf("for <%q> expected %q (error: %v), got %q (error: %v)", d.in, d.expected, d.err, got, err)
+		}
+		if d.err && (!errors.Is(err, ErrTooManyColumns) || err.Error() != "line 2, offset 8: too many fields in row") {
+			t.Errorf("for <%q> expected ErrTooManyColumns at line 2, got %v", d.in, err)
+		}
+	}
+}
+
+func TestCommentCollectorReuse(t *testing.T) {
+	sc := New(nil, WithCommentIndent(true)).(*scanner)
+	c := sc.commentCollector
+	sc.Options(WithComment([]byte("//")))
+	if sc.commentCollector != c {
+		t.Errorf("expected the comment collector to be reused")
+	}
+	sc.Options(WithComment(nil))
+	if sc.commentCollector != nil {
+		t.Errorf("expected no comment collector for an empty prefix")
+	}
+	sc.Options(WithComment([]byte("#")))
+	if sc.commentCollector == nil {
+		t.Errorf("expected a comment collector for a non empty prefix")
+	}
+	if got := scanFields("  //a\n#b\n", WithCommentIndent(true), WithComment([]byte("//"))); strings.Join(got, "|") != "a|#b" {
+		t.Errorf("expected [a #b], got %q", got)
+	}
+}
+
+func TestNewBytes(t *testing.T) {
+	data := []struct {
+		in      string
+		options []Option
+	}{
+		{"", nil},
+		{"a,b\nc,d", nil},
+		{"a,\"b,\"\"c\"\n# d\n\n e ,f\r\n", nil},
+		{"a\x00b,\"\x00c\"\n", []Option{WithStripNulls()}},
+		{"a;b\x1ec;d\x1e", []Option{WithSeparator(';'), WithRecordSeparator('\x1e')}},
+	}
+	for _, d := range data {
+		expected := scanFields(d.in, d.options...)
+		in := []byte(d.in)
+		sc := NewBytes(in, d.options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") || sc.Err() != nil {
+			t.Errorf("for <%q> expected %q, got %q (error: %v)", d.in, expected, got, sc.Err())
+		}
+		if string(in) != d.in {
+			t.Errorf("the input <%q> was modified to <%q>", d.in, in)
+		}
+	}
+	// the unquoted fields alias the input
+	in := []byte("a,\"b\"\nc")
+	sc := NewBytes(in)
+	fields := [][]byte{}
+	for sc.Scan() {
+		fields = append(fields, sc.Bytes())
+	}
+	if fmt.Sprintf("%q", fields) != `["a" "b" "c"]` {
+		t.Errorf("expected [a b c], got %q", fields)
+	}
+	in[0] = 'x'
+	if string(fields[0]) != "x" {
+		t.Errorf("expected the first field to alias the input, got %q", fields[0])
+	}
+}
+
+func TestDecimalComma(t *testing.T) {
+	in := "1,5;-0,25;\"2,5\";1,2,3;a,b;12;,5;5,;+3,0\n# 4,5\n"
+	expected := "1.5|-0.25|2,5|1,2,3|a,b|12|,5|5,|+3.0| 4,5"
+	options := []Option{WithSeparator(';'), WithDecimalComma()}
+	if got := scanFields(in, options...); strings.Join(got, "|") != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	data := []byte(in)
+	sc := NewBytes(data, options...)
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != expected || string(data) != in {
+		t.Errorf("expected %q (input unchanged), got %q (input <%q>)", expected, got, data)
+	}
+}
+
+func TestMixedLineEndings(t *testing.T) {
+	in := "a,b\r\n\r\n# c\r\n\"d\",e\n \r\n\"f\r\ng\" , h\r\n# i\nj\r\r\n\"k\"\r"
+	data := []struct {
+		options  []Option
+		expected []string
+	}{
+		{nil, []string{"a", "b", "", " c", "d", "e", " ", "f\r\ng", " h", " i", "j\r", "k"}},
+		{[]Option{WithNormalizeLineEndings()}, []string{"a", "b", "", " c", "d", "e", " ", "f\ng", " h", " i", "j\n", "k"}},
+	}
+	for _, d := range data {
+		sc := NewBytes([]byte(in), d.options...)
+		got := []string{}
+		empty := 0
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+			if sc.IsEmptyLine() {
+				empty++
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") || empty != 2 {
+			t.Errorf("expected %q with 2 empty lines, got %q with %d empty lines", d.expected, got, empty)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	n := len(DefaultOptions)
+	options := Defaults(WithSeparator(';'), WithFuzzyWhitespace([]byte(" \r")))
+	options = append(options, WithQuote('\'', QuoteFuzzy))
+	if len(DefaultOptions) != n || len(options) != n+3 {
+		t.Errorf("expected %d default options and %d options, got %d and %d", n, n+3, len(DefaultOptions), len(options))
+	}
+	if got := scanFields("\r'a,b'\r;c\n", options...); strings.Join(got, "|") != "a,b|c" {
+		t.Errorf("expected [a,b c], got %q", got)
+	}
+}
+
+func TestCommentPrefix(t *testing.T) {
+	sc := New(strings.NewReader("//a\nb\n"), WithComment([]byte("//")))
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, fmt.Sprintf("%q", sc.CommentPrefix()))
+	}
+	if strings.Join(got, " ") != `"//" ""` {
+		t.Errorf(`expected ["//" ""], got %v`, got)
+	}
+}
+
+func TestComments(t *testing.T) {
+	sc := New(strings.NewReader("#a\n//b\n///c\nd,#e\n"), WithComments([]byte("//"), nil, []byte("#"), []byte("///")))
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, fmt.Sprintf("%s:%s", sc.CommentPrefix(), sc.Bytes()))
+	}
+	if strings.Join(got, "|") != "#:a|//:b|///:c|:d|:#e" {
+		t.Errorf("expected [#:a //:b ///:c :d :#e], got %q", got)
+	}
+	if string(sc.Comment()) != "//" || len(sc.Comments()) != 3 {
+		t.Errorf("expected the prefix // of 3 prefixes, got %q of %q", sc.Comment(), sc.Comments())
+	}
+	if sc := New(nil, WithComments()); sc.Comment() != nil || sc.Comments() != nil {
+		t.Errorf("expected no comment prefix, got %q", sc.Comments())
+	}
+}
+
+func TestEscapedSeparators(t *testing.T) {
+	in := "a\\,b,c\\\\,\"d\\\"e\",f\\\\\\,g\\,\n\\,h\\x\n#i\\,j\n"
+	expected := []string{"a,b", "c\\", "d\"e", "f\\,g,", ",h\\x", "i\\,j"}
+	options := []Option{WithEscape('\\'), WithEscapedSeparators()}
+	if got := scanFields(in, options...); strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	data := []byte(in)
+	sc := NewBytes(data, options...)
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != strings.Join(expected, "|") || string(data) != in {
+		t.Errorf("expected %q (input unchanged), got %q (input <%q>)", expected, got, data)
+	}
+}
+
+func TestRowCount(t *testing.T) {
+	sc := New(strings.NewReader("# c\na,b\n\nc\n\"d\ne\",f"))
+	got := []int{}
+	for sc.Scan() {
+		got = append(got, sc.RowCount())
+	}
+	if fmt.Sprint(got) != "[0 0 1 1 2 2 3]" {
+		t.Errorf("expected [0 0 1 1 2 2 3], got %v", got)
+	}
+}
+
+func TestPhysicalLineRecordNumber(t *testing.T) {
+	in := "# c\na,\"b\nc\"\n\n\"d\n\ne\",f\ng"
+	for _, options := range [][]Option{nil, {WithRowBuffering()}} {
+		sc := New(strings.NewReader(in), options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, fmt.Sprintf("%d:%d", sc.PhysicalLine(), sc.RecordNumber()))
+		}
+		if expected := "[1:0 2:1 2:1 4:0 5:2 7:2 8:3]"; fmt.Sprint(got) != expected {
+			t.Errorf("expected %s, got %v", expected, got)
+		}
+	}
+}
+
+func TestSkipBOM(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"\xEF\xBB\xBFa,b\n", nil, []string{"\xEF\xBB\xBFa", "b"}},
+		{"\xEF\xBB\xBFa,b\n", []Option{WithSkipBOM(false)}, []string{"\xEF\xBB\xBFa", "b"}},
+		{"\xEF\xBB\xBFa,b\n", []Option{WithSkipBOM(true)}, []string{"a", "b"}},
+		{"\xEF\xBB\xBF\"a\",\xEF\xBB\xBFb\n", []Option{WithSkipBOM(true)}, []string{"a", "\xEF\xBB\xBFb"}},
+		{"\xEF\xBB\xBF#c\n", []Option{WithSkipBOM(true)}, []string{"c"}},
+	}
+	for _, d := range data {
+		got := scanFields(d.in, d.options...)
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+	// the offsets are in the input
+	sc := New(strings.NewReader("\xEF\xBB\xBF\"a\",b\n"), WithSkipBOM(true))
+	got := []string{}
+	for sc.Scan() {
+		openAt, _ := sc.QuoteSpans()
+		got = append(got, fmt.Sprint(sc.Offset(), openAt))
+	}
+	if strings.Join(got, "|") != "0 3|7 -1" {
+		t.Errorf("expected offsets [0 3] and [7 -1], got %q", got)
+	}
+}
+
+func TestSkipRepeatedHeader(t *testing.T) {
+	sc := New(strings.NewReader("# c\na,b\n1,2\n\na,b\n3,4\na,b,c\n\"a\",b\n"), WithSkipRepeatedHeader())
+	got := []string{}
+	for {
+		row, ok := sc.NextRow()
+		if !ok {
+			break
+		}
+		got = append(got, fmt.Sprintf("%q", row.Fields()))
+	}
+	expected := `[" c"]|["a" "b"]|["1" "2"]|[""]|["3" "4"]|["a" "b" "c"]`
+	if strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestReadInto(t *testing.T) {
+	in := "# c\na,b\n\nc,\"d\ne\",f\n"
+	for _, options := range [][]Option{nil, {WithUnsafeStrings()}} {
+		sc := New(strings.NewReader(in), options...)
+		row := make([]string, 0, 1)
+		got := []string{}
+		var err error
+		for err = sc.ReadInto(&row); err == nil; err = sc.ReadInto(&row) {
+			got = append(got, fmt.Sprintf("%q", row))
+		}
+		if err != io.EOF || strings.Join(got, "|") != `["a" "b"]|["c" "d\ne" "f"]` {
+			t.Errorf("expected [a b] [c d\\ne f] and EOF, got %s and %v", strings.Join(got, "|"), err)
+		}
+	}
+	// without unsafe strings, the row reading needs only the allocations of the strings
+	sc := New(strings.NewReader(strings.Repeat("ab,cd\n", 1000)))
+	row := make([]string, 0, 2)
+	if allocs := testing.AllocsPerRun(100, func() { sc.ReadInto(&row) }); allocs > 2 {
+		t.Errorf("expected at most 2 allocations per row, got %v", allocs)
+	}
+	sc = New(strings.NewReader(strings.Repeat("ab,cd\n", 1000)), WithUnsafeStrings())
+	if allocs := testing.AllocsPerRun(100, func() { sc.ReadInto(&row) }); allocs > 0 {
+		t.Errorf("expected no allocations with unsafe strings, got %v", allocs)
+	}
+}
+
+func TestKeepEmptyLines(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected string
+	}{
+		{"a,b\n\nc\n\n\n", nil, `["a" "b"]|["c"]`},
+		{"a,b\n\nc\n\n\n", []Option{WithKeepEmptyLines()}, `["a" "b"]|[]|["c"]|[]|[]`},
+		{"\n#x\n \na\n", []Option{WithKeepEmptyLines()}, `[]|[]|["a"]`},
+		{"a\n\"\"\n\n", []Option{WithKeepEmptyLines()}, `["a"]|[""]|[]`},
+		{"a\n\nb", []Option{WithKeepEmptyLines(), WithColumns(1)}, `[""]|[]|[""]`},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(d.in), d.options...)
+		row := []string{}
+		got := []string{}
+		var err error
+		for err = sc.ReadInto(&row); err == nil; err = sc.ReadInto(&row) {
+			got = append(got, fmt.Sprintf("%q", row))
+		}
+		if err != io.EOF || strings.Join(got, "|") != d.expected {
+			t.Errorf("for <%q> expected %s and EOF, got %s and %v", d.in, d.expected, strings.Join(got, "|"), err)
+		}
+	}
+}
+
+func TestReadIntoTrailingNewlines(t *testing.T) {
+	data := []struct {
+		in       string
+		expected string // without and with WithKeepEmptyLines
+		keep     string
+	}{
+		{"a,b", `["a" "b"]`, `["a" "b"]`},
+		{"a,b\n", `["a" "b"]`, `["a" "b"]`},
+		{"a,b\r\n", `["a" "b"]`, `["a" "b"]`},
+		{"a,b\n\n", `["a" "b"]`, `["a" "b"]|[]`},
+		{"a,b\n\n\n", `["a" "b"]`, `["a" "b"]|[]|[]`},
+		{"a,b\n  ", `["a" "b"]`, `["a" "b"]|[]`},
+		{"a,b\n \t\n", `["a" "b"]`, `["a" "b"]|[]`},
+		{"\n", ``, `[]`},
+		{"", ``, ``},
+		{"a,b\n\"\"\n\n", `["a" "b"]`, `["a" "b"]|[""]|[]`},
+	}
+	for _, d := range data {
+		for i, options := range [][]Option{nil, {WithKeepEmptyLines()}} {
+			sc := New(strings.NewReader(d.in), options...)
+			row := []string{}
+			got := []string{}
+			var err error
+			for err = sc.ReadInto(&row); err == nil; err = sc.ReadInto(&row) {
+				got = append(got, fmt.Sprintf("%q", row))
+			}
+			expected := d.expected
+			if i == 1 {
+				expected = d.keep
+			}
+			if err != io.EOF || strings.Join(got, "|") != expected {
+				t.Errorf("for <%q> (keep %v) expected %s and EOF, got %s and %v", d.in, i == 1, expected, strings.Join(got, "|"), err)
+			}
+		}
+	}
+}
+
+func TestRaggedHandler(t *testing.T) {
+	// merge the extra fields in the last one and pad the short rows
+	merge := func(fields [][]byte, expected int) [][]byte {
+		for len(fields) < expected {
+			fields = append(fields, nil)
+		}
+		if len(fields) > expected {
+			fields[expected-1] = bytes.Join(fields[expected-1:], []byte{','})
+			fields = fields[:expected]
+		}
+		return fields
+	}
+	sc := New(strings.NewReader("a,b,c\n1,2,3,4\n\n5\n6,7,8\n"), WithRaggedHandler(merge))
+	got := []string{}
+	row := []string{}
+	for sc.ReadInto(&row) == nil {
+		got = append(got, fmt.Sprintf("%q", row))
+	}
+	expected := `["a" "b" "c"]|["1" "2" "3,4"]|["5" "" ""]|["6" "7" "8"]`
+	if strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestRowBuffering(t *testing.T) {
+	in := "a,b,c\n# x\n1,\"2\n3\",4,5\n\n6"
+	sc := New(strings.NewReader(in), WithRowBuffering())
+	ref := New(strings.NewReader(in))
+	got := []string{}
+	for sc.Scan() {
+		if !ref.Scan() {
+			t.Fatalf("expected the same fields as without buffering")
+		}
+		if !bytes.Equal(sc.Bytes(), ref.Bytes()) || sc.Offset() != ref.Offset() || sc.AtRowEnd() != ref.AtRowEnd() ||
+			sc.IsComment() != ref.IsComment() || sc.IsQuoted() != ref.IsQuoted() || sc.RowCount() != ref.RowCount() {
+			t.Errorf("field %q differs from %q without buffering", sc.Bytes(), ref.Bytes())
+		}
+		if ref.RowColumns() != -1 && ref.RowColumns() != sc.RowColumns() {
+			t.Errorf("expected %d columns, got %d", ref.RowColumns(), sc.RowColumns())
+		}
+		got = append(got, fmt.Sprintf("%s:%d", sc.Bytes(), sc.RowColumns()))
+	}
+	if ref.Scan() {
+		t.Errorf("expected the same fields as without buffering")
+	}
+	expected := "a:3|b:3|c:3| x:1|1:4|2\n3:4|4:4|5:4|:1|6:1"
+	if strings.Join(got, "|") != expected {
+		t.Errorf("expected %q, got %q", expected, strings.Join(got, "|"))
+	}
+
+	// the fields before an error are delivered
+	sc = New(strings.NewReader("a,b\n1,2,3\n"), WithRowBuffering(), WithMaxColumns(2))
+	got = got[:0]
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != "a|b|1|2" || !errors.Is(sc.Err(), ErrTooManyColumns) {
+		t.Errorf("expected a|b|1|2 and ErrTooManyColumns, got %q and %v", got, sc.Err())
+	}
+}
+
+func TestColumnCountHistogram(t *testing.T) {
+	sc := New(strings.NewReader("a,b,c\n#x,y\n1,2\n\n3,4,5\n6,7,8\n9"), WithColumnCountHistogram())
+	for sc.Scan() {
+	}
+	if got, expected := fmt.Sprint(sc.ColumnCountHistogram()), "map[1:1 2:1 3:3]"; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+	if New(strings.NewReader("a")).ColumnCountHistogram() != nil {
+		t.Errorf("expected nil histogram without WithColumnCountHistogram")
+	}
+}
+
+func TestExcelSepDirective(t *testing.T) {
+	data := []struct {
+		in       string
+		expected string
+	}{
+		{"sep=;\na;b,c\n1;2\n", "a@6:2|b,c@8:2|1@12:3|2@14:3"},
+		{"sep=;\r\na;b\n", "a@7:2|b@9:2"},
+		{"sep=|", ""},
+		{"sep=;x\na;b\n", "sep=;x@0:1|a;b@7:2"},
+		{"a,b\n", "a@0:1|b@2:1"},
+		{"sep", "sep@0:1"},
+	}
+	for _, d := range data {
+		for _, sc := range []Scanner{
+			New(strings.NewReader(d.in), WithExcelSepDirective()),
+			NewBytes([]byte(d.in), WithExcelSepDirective()),
+		} {
+			got := []string{}
+			for sc.Scan() {
+				got = append(got, fmt.Sprintf("%s@%d:%d", sc.Bytes(), sc.Offset(), sc.(*scanner).line))
+			}
+			if strings.Join(got, "|") != d.expected {
+				t.Errorf("for %q expected %q, got %q", d.in, d.expected, strings.Join(got, "|"))
+			}
+		}
+	}
+}
+
+func TestInitialBufferSize(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+	sc := New(strings.NewReader(long+","+long), WithInitialBufferSize(16))
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if len(got) != 2 || got[0] != long || got[1] != long || sc.Err() != nil {
+		t.Errorf("expected two long fields, got %d fields (error: %v)", len(got), sc.Err())
+	}
+	// the maximal size is at least n
+	huge := strings.Repeat("x", bufio.MaxScanTokenSize+1)
+	sc = New(strings.NewReader(huge), WithInitialBufferSize(len(huge)+1))
+	if !sc.Scan() || len(sc.Bytes()) != len(huge) {
+		t.Errorf("expected a huge field (error: %v)", sc.Err())
+	}
+}
+
+func BenchmarkInitialBufferSize(b *testing.B) {
+	// ~1MB sample with 64KB fields
+	line := strings.Repeat("x", 64<<10) + "," + strings.Repeat("y", 64<<10) + "\n"
+	sample := strings.Repeat(line, 8)
+	for _, n := range []int{0, 96 << 10} {
+		b.Run(fmt.Sprintf("initial-%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(sample)))
+			for i := 0; i < b.N; i++ {
+				sc := New(strings.NewReader(sample), WithInitialBufferSize(n))
+				for sc.Scan() {
+				}
+			}
+		})
+	}
+}
+
+func TestColumns(t *testing.T) {
+	sc := New(strings.NewReader("a,b,c,d\n#x\n1,2,3,4\n5,6\n"), WithColumns(3, 0))
+	got := []string{}
+	row := []string{}
+	for sc.ReadInto(&row) == nil {
+		got = append(got, fmt.Sprintf("%q", row))
+	}
+	expected := `["d" "a"]|["4" "1"]|["" "5"]`
+	if strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestQuoteBeforeComment(t *testing.T) {
+	data := []struct {
+		options  []Option
+		expected string
+	}{
+		{nil, `"#x"q|"y"|" c"c`},
+		{[]Option{WithComment([]byte(`"#`))}, `"#x"q|"y"|"# c"`},
+		{[]Option{WithComment([]byte(`"`)), WithCommentIndent(true)}, `"#x"q|"y"|"# c"`},
+		{[]Option{WithQuotePair([]byte("<<"), []byte(">>")), WithComment([]byte("<"))}, `"\"#x\""|"y"|"# c"`},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader("\"#x\",y\n# c\n"), d.options...)
+		got := []string{}
+		for sc.Scan() {
+			f := fmt.Sprintf("%q", sc.Bytes())
+			if sc.IsQuoted() {
+				f += "q"
+			}
+			if sc.IsComment() {
+				f += "c"
+			}
+			got = append(got, f)
+		}
+		if strings.Join(got, "|") != d.expected {
+			t.Errorf("for %v expected %s, got %s", d.options, d.expected, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestReadCellsInto(t *testing.T) {
+	sc := New(strings.NewReader("id,tags,name\n1,a|b,x|y\n2,,z\n3,\"c,d|e\"\n"), WithSubSeparator('|', 1))
+	got := []string{}
+	cells := [][]string{}
+	for sc.ReadCellsInto(&cells) == nil {
+		got = append(got, fmt.Sprintf("%q", cells))
+	}
+	expected := `[["id"] ["tags"] ["name"]]|[["1"] ["a" "b"] ["x|y"]]|[["2"] [] ["z"]]|[["3"] ["c,d" "e"]]`
+	if strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestFuzzyQuoteTSV(t *testing.T) {
+	in := "  \"a b\"  \t \"c\"\tx\n\"d\te\"\t\"\tf\t\"\t \"g\" \n"
+	expected := "a b|c|x|d\te|\tf\t|g"
+	for _, opts := range [][]Option{
+		{WithSeparator('\t'), WithQuote('"', QuoteFuzzy)},
+		{WithSeparator('\t'), WithQuote('"', QuoteFuzzy), WithFuzzyWhitespace([]byte("\t "))},
+	} {
+		sc := New(strings.NewReader(in), opts...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != expected {
+			t.Errorf("expected %q, got %q", expected, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestChunkObserver(t *testing.T) {
+	got := []string{}
+	observer := func(chunk []byte, atRowEnd bool) {
+		got = append(got, fmt.Sprintf("%q:%v", chunk, atRowEnd))
+	}
+	sc := New(strings.NewReader("a,\"b,c\"\n#d,e\nf"), WithChunkObserver(observer))
+	fields := 0
+	for sc.Scan() {
+		fields++
+	}
+	expected := `"a,":false|"\"b,":false|"c\"\n":true|"#d,":false|"e\n":true|"f\n":true`
+	if strings.Join(got, "|") != expected || fields != 4 {
+		t.Errorf("expected %s and 4 fields, got %s and %d fields", expected, strings.Join(got, "|"), fields)
+	}
+}
+
+func TestEscapeStates(t *testing.T) {
+	in := `"a""b","c\"d",e` + "\n"
+	data := []struct {
+		options  []Option
+		expected string
+	}{
+		{nil, `a"b|c\"d|e`},
+		{[]Option{WithEscape('"')}, `a"b|c\"d|e`},
+		{[]Option{WithEscape('\\')}, `a""b|c"d|e`},
+		{[]Option{WithEscape(0)}, `a""b|c\"d|e`},
+		// WithQuote resets the escape to the quote
+		{[]Option{WithEscape(0), WithQuote('"', QuoteStrict)}, `a"b|c\"d|e`},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(in), d.options...)
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != d.expected {
+			t.Errorf("expected %s, got %s", d.expected, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestHeaderTransform(t *testing.T) {
+	in := "\ufeff Id ,Name\n#c\n Id ,Name\nx,y\n"
+	data := []struct {
+		options  []Option
+		expected string
+	}{
+		{nil, `["\ufeff Id " "Name"]|[" Id " "Name"]|["x" "y"]`},
+		{[]Option{WithHeaderTransform(TrimHeaderName)}, `["Id" "Name"]|[" Id " "Name"]|["x" "y"]`},
+		{[]Option{WithHeaderTransform(func(s string) string { return strings.ToLower(TrimHeaderName(s)) })}, `["id" "name"]|[" Id " "Name"]|["x" "y"]`},
+	}
+	for _, d := range data {
+		sc := New(strings.NewReader(in), d.options...)
+		got := []string{}
+		row := []string{}
+		for sc.ReadInto(&row) == nil {
+			got = append(got, fmt.Sprintf("%q", row))
+		}
+		if strings.Join(got, "|") != d.expected {
+			t.Errorf("expected %s, got %s", d.expected, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestStopAtComment(t *testing.T) {
+	in := "a,b\n# note\n1,2\n#  END \nnot,csv,\"\n"
+	sc := New(strings.NewReader(in), WithStopAtComment([]byte("END")))
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != "a|b| note|1|2" || sc.Err() != nil || sc.Scan() {
+		t.Errorf("expected a|b| note|1|2 without error, got %q (error: %v)", got, sc.Err())
+	}
+}
+
+func TestRawComments(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+	}{
+		{"# a\nb,c\n//d,e\n", []Option{WithComments([]byte("#"), []byte("//"))}, []string{" a", "b", "c", "d,e"}},
+		{"# a\nb,c\n//d,e\n", []Option{WithComments([]byte("#"), []byte("//")), WithRawComments()}, []string{"# a", "b", "c", "//d,e"}},
+		{"  #a\r\n#b", []Option{WithCommentIndent(true), WithRawComments()}, []string{"  #a", "#b"}},
+		{"#a\n#  END\nb\n", []Option{WithRawComments(), WithStopAtComment([]byte("END"))}, []string{"#a"}},
+	}
+	for _, d := range data {
+		got := []string{}
+		sc := New(strings.NewReader(d.in), d.options...)
+		for sc.Scan() {
+			got = append(got, string(sc.Bytes()))
+		}
+		if strings.Join(got, "|") != strings.Join(d.expected, "|") {
+			t.Errorf("for <%q> expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestScanUntil(t *testing.T) {
+	sc := New(strings.NewReader("a,b\nSTOP,c\nd,STOP\ne\n"))
+	found := 0
+	stop := func(field []byte, atRowEnd bool) bool {
+		if string(field) == "STOP" {
+			found++
+			return true
+		}
+		return false
+	}
+	got := []string{}
+	for sc.ScanUntil(stop) {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != "a|b" || found != 1 || string(sc.Bytes()) != "STOP" || !sc.AtRowStart() || sc.Err() != nil {
+		t.Errorf("expected a|b and a stop at the first STOP, got %q, %d matches and <%q> (error: %v)", got, found, sc.Bytes(), sc.Err())
+	}
+	// continue after the match, up to the end of the input
+	got = got[:0]
+	for sc.ScanUntil(stop) {
+		got = append(got, string(sc.Bytes()))
+	}
+	if strings.Join(got, "|") != "c|d" || found != 2 || !sc.AtRowEnd() {
+		t.Errorf("expected c|d and a stop at the second STOP, got %q and %d matches", got, found)
+	}
+	for sc.ScanUntil(stop) {
+	}
+	if found != 2 || sc.Err() != nil {
+		t.Errorf("expected the end of the input, got %d matches (error: %v)", found, sc.Err())
+	}
+}
+
+func TestScanPlain(t *testing.T) {
+	plain := []Option{WithQuote(0, nil), WithComment(nil)}
+	data := []string{
+		"",
+		"a,b\nc,d\n",
+		"a,b\r\n\r\nc,\n",
+		"a,b\nc,",
+		",\n,,\n\"a,b\",#c",
+	}
+	// the fields and their state are the same for the plain and the general scan
+	state := func(sc Scanner) string {
+		openAt, closeAt := sc.QuoteSpans()
+		return fmt.Sprintf("%q %d %d %v %v %v %d %d %d %d", sc.Bytes(), sc.Offset(), sc.RowCount(),
+			sc.AtRowStart(), sc.AtRowEnd(), sc.IsEmptyLine(), sc.RowColumns(), openAt, closeAt, sc.(*scanner).line)
+	}
+	for _, in := range data {
+		for _, bytesInput := range []bool{false, true} {
+			var sc, general Scanner
+			if bytesInput {
+				sc, general = NewBytes([]byte(in), plain...), NewBytes([]byte(in), plain...)
+			} else {
+				sc, general = New(strings.NewReader(in), plain...), New(strings.NewReader(in), plain...)
+			}
+			if !sc.(*scanner).plain {
+				t.Fatalf("expected the plain scan")
+			}
+			general.(*scanner).plain = false
+			for {
+				ok, gok := sc.Scan(), general.Scan()
+				if ok != gok {
+					t.Fatalf("for <%q> expected Scan() %v, got %v", in, gok, ok)
+				}
+				if !ok {
+					break
+				}
+				if got, expected := state(sc), state(general); got != expected {
+					t.Errorf("for <%q> expected %s, got %s", in, expected, got)
+				}
+			}
+			if sc.Truncated() != general.Truncated() {
+				t.Errorf("for <%q> expected Truncated() %v, got %v", in, general.Truncated(), sc.Truncated())
+			}
+		}
+	}
+	// the default options need the general scan
+	if New(strings.NewReader("")).(*scanner).plain {
+		t.Errorf("expected the general scan for the default options")
+	}
+}
+
+func BenchmarkScanPlain(b *testing.B) {
+	// ~1MB sample with short unquoted fields
+	line := "12345,some text,3.14,another field,2024-01-01,x\n"
+	sample := strings.Repeat(line, (1<<20)/len(line))
+	for _, plain := range []bool{true, false} {
+		b.Run(fmt.Sprintf("plain-%v", plain), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(sample)))
+			for i := 0; i < b.N; i++ {
+				sc := New(strings.NewReader(sample), WithQuote(0, nil), WithComment(nil))
+				sc.(*scanner).plain = plain
+				for sc.Scan() {
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrExtraData is the error returned by SplitLine
+// if the line contains more than one record (and by ParseField if it contains more than one field).
+var ErrExtraData = errors.New("extra data after the first record")
+
+// SplitLine splits a single record into fields without the need to create a Scanner.
+// The options are the same as for New (DefaultOptions are applied first).
+// A trailing line break is allowed, but an error is returned
+// if some quoted field is not closed or if line contains more than one record.
+// The returned fields do not share memory with line.
+func SplitLine(line []byte, opts ...Option) ([][]byte, error) {
+	s := New(bytes.NewReader(line), opts...)
+	var fields [][]byte
+	for s.Scan() {
+		if s.IsQuoted() {
+			if _, closeAt := s.QuoteSpans(); closeAt == -1 {
+				return nil, ErrUnterminatedQuote
+			}
+		}
+		fields = append(fields, append([]byte{}, s.Bytes()...))
+		if s.AtRowEnd() {
+			break
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if s.Scan() {
+		return nil, ErrExtraData
+	}
+	return fields, s.Err()
+}
+
+// ParseField decodes a single raw field (with its quotes and the spaces around them) as the scanner does,
+// with the same options as New (DefaultOptions are applied first, the comments are disabled).
+// It could be used to check the quote and escape settings of a dialect on sample fields.
+// quoted is true if the field is quoted. A trailing line break is allowed, but an error is returned
+// if the quoted field is not closed or if raw contains more than one field.
+// The returned value does not share memory with raw.
+func ParseField(raw []byte, opts ...Option) (value []byte, quoted bool, err error) {
+	opts = append(opts[:len(opts):len(opts)], WithComment(nil))
+	s := New(bytes.NewReader(raw), opts...)
+	if !s.Scan() {
+		return nil, false, s.Err()
+	}
+	quoted = s.IsQuoted()
+	if _, closeAt := s.QuoteSpans(); quoted && closeAt == -1 {
+		return nil, true, ErrUnterminatedQuote
+	}
+	value = append([]byte{}, s.Bytes()...)
+	if !s.AtRowEnd() || s.Scan() {
+		return nil, false, ErrExtraData
+	}
+	return value, quoted, s.Err()
+}
+
+// Unquote removes the surrounding quotes of field and unescapes the quotes inside,
+// like the scanner does for the quoted fields (<escape><quote> → <quote>).
+// If field is not enquoted (or if its closing quote is escaped), it is returned unchanged.
+// Otherwise the returned value does not share memory with field.
+func Unquote(field []byte, quote, escape byte) []byte {
+	if quote == 0 || len(field) < 2 || field[0] != quote || field[len(field)-1] != quote {
+		return field
+	}
+	if escape != 0 {
+		// the closing quote should not be escaped (by doubling it if escape is quote)
+		n := 0
+		for i := len(field) - 2; i > 0 && field[i] == escape; i-- {
+			n++
+		}
+		if n%2 == 1 {
+			return field
+		}
+	}
+	s := &scanner{quote: quote, escape: escape}
+	s.value = append([]byte{}, field[1:len(field)-1]...)
+	s.unescapeQuotes()
+	return s.value
+}
+
+// SplitField splits a field packing a list of values with the sub-separator subsep (like "a|b|c").
+// The sub-separator can not be escaped. An empty field is an empty list (nil).
+// The returned values are slices of field.
+func SplitField(field []byte, subsep byte) [][]byte {
+	if len(field) == 0 {
+		return nil
+	}
+	return bytes.Split(field, []byte{subsep})
+}
+
+// SplitKeyValue splits a field like "key=value" at the first sep (like '=').
+// The value could contain sep. If field contains no sep, ok is false.
+// The key and the value are slices of field.
+func SplitKeyValue(field []byte, sep byte) (key, value []byte, ok bool) {
+	i := bytes.IndexByte(field, sep)
+	if i == -1 {
+		return nil, nil, false
+	}
+	return field[:i], field[i+1:], true
+}
+
+// KeyValues returns the map key → value of the fields like "key=value" (split with SplitKeyValue).
+// The fields without sep are ignored, and for a repeated key the last value is kept.
+// It could be used with the fields returned by Row.Fields() or SplitLine.
+func KeyValues(fields [][]byte, sep byte) map[string]string {
+	m := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if key, value, ok := SplitKeyValue(field, sep); ok {
+			m[string(key)] = string(value)
+		}
+	}
+	return m
+}
@@ -0,0 +1,140 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected []string
+		err      error
+	}{
+		{"", nil, []string{}, nil},
+		{"a,b,c", nil, []string{"a", "b", "c"}, nil},
+		{"a,\"b,\"\"c\"\"\",d\n", nil, []string{"a", `b,"c"`, "d"}, nil},
+		{"a;\"b\nc\";\n", []Option{WithSeparator(';')}, []string{"a", "b\nc", ""}, nil},
+		{"a,\"b,c", nil, nil, ErrUnterminatedQuote},
+		{"a,b\nc,d\n", nil, nil, ErrExtraData},
+	}
+	for _, d := range data {
+		fields, err := SplitLine([]byte(d.in), d.options...)
+		got := []string{}
+		for _, f := range fields {
+			got = append(got, string(f))
+		}
+		if !errors.Is(err, d.err) || (err == nil && fmt.Sprintf("%q", got) != fmt.Sprintf("%q", d.expected)) {
+			t.Errorf("for %q expected %q (error: %v), got %q (error: %v)", d.in, d.expected, d.err, got, err)
+		}
+	}
+}
+
+func TestParseField(t *testing.T) {
+	data := []struct {
+		in       string
+		options  []Option
+		expected string
+		quoted   bool
+		err      error
+	}{
+		{"", nil, "", false, nil},
+		{"a b", nil, "a b", false, nil},
+		{"#a", nil, "#a", false, nil},
+		{` "a""b" `, nil, `a"b`, true, nil},
+		{"\"a\nb\"\r\n", nil, "a\nb", true, nil},
+		{`"a\"b"`, []Option{WithQuote('"', QuoteStrict), WithEscape('\\')}, `a"b`, true, nil},
+		{`"a""b"`, []Option{WithQuote('"', QuoteStrict), WithEscape(0)}, `a""b`, true, nil},
+		{`'a''b'`, []Option{WithQuote('\'', QuoteStrict)}, `a'b`, true, nil},
+		{`“a””b”`, []Option{WithQuotePair([]byte("“"), []byte("”"))}, `a”b`, true, nil},
+		{`"a`, nil, "", true, ErrUnterminatedQuote},
+		{"a,b", nil, "", false, ErrExtraData},
+		{"a\nb", nil, "", false, ErrExtraData},
+	}
+	for _, d := range data {
+		value, quoted, err := ParseField([]byte(d.in), d.options...)
+		if !errors.Is(err, d.err) || (err == nil && (string(value) != d.expected || quoted != d.quoted)) {
+			t.Errorf("for %q expected %q %v (error: %v), got %q %v (error: %v)", d.in, d.expected, d.quoted, d.err, value, quoted, err)
+		}
+	}
+}
+
+func TestSplitKeyValue(t *testing.T) {
+	data := []struct {
+		in         string
+		key, value string
+		ok         bool
+	}{
+		{"", "", "", false},
+		{"a", "", "", false},
+		{"a=", "a", "", true},
+		{"=b", "", "b", true},
+		{"a=b=c", "a", "b=c", true},
+	}
+	for _, d := range data {
+		key, value, ok := SplitKeyValue([]byte(d.in), '=')
+		if string(key) != d.key || string(value) != d.value || ok != d.ok {
+			t.Errorf("for %q expected %q %q %v, got %q %q %v", d.in, d.key, d.value, d.ok, key, value, ok)
+		}
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	fields, _ := SplitLine([]byte("a=1,b=x=y,c,a=2\n"))
+	if got := fmt.Sprint(KeyValues(fields, '=')); got != "map[a:2 b:x=y]" {
+		t.Errorf("expected map[a:2 b:x=y], got %s", got)
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	data := []struct {
+		in       string
+		quote    byte
+		escape   byte
+		expected string
+	}{
+		{`abc`, '"', '"', `abc`},
+		{`"abc"`, '"', '"', `abc`},
+		{`"a""b""c"`, '"', '"', `a"b"c`},
+		{`""`, '"', '"', ``},
+		{`"`, '"', '"', `"`},
+		{`"abc`, '"', '"', `"abc`},
+		{`"a""`, '"', '"', `"a""`},
+		{`""""`, '"', '"', `"`},
+		{`"""`, '"', '"', `"""`},
+		{`'a''b'`, '\'', '\'', `a'b`},
+		{`"a\"b"`, '"', '\\', `a"b`},
+		{`"a\\"`, '"', '\\', `a\\`},
+		{`"a\"`, '"', '\\', `"a\"`},
+		{`"a""b"`, '"', 0, `a""b`},
+		{`"abc"`, 0, 0, `"abc"`},
+	}
+	for _, d := range data {
+		if got := Unquote([]byte(d.in), d.quote, d.escape); string(got) != d.expected {
+			t.Errorf("for %s expected %s, got %s", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestSplitField(t *testing.T) {
+	data := []struct {
+		in       string
+		expected []string
+	}{
+		{"", []string{}},
+		{"a", []string{"a"}},
+		{"a|b||c", []string{"a", "b", "", "c"}},
+		{"|", []string{"", ""}},
+	}
+	for _, d := range data {
+		got := []string{}
+		for _, f := range SplitField([]byte(d.in), '|') {
+			got = append(got, string(f))
+		}
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", d.expected) {
+			t.Errorf("for %q expected %q, got %q", d.in, d.expected, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// UTF-16 byte order marks.
+var (
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// WithAutoUTF16 makes the scanner detect a UTF-16 byte order mark at the start of the input
+// (\xFF\xFE for little endian or \xFE\xFF for big endian) and then decode the input to UTF-8, without the BOM.
+// The input without such BOM is read as it is.
+// The offsets and the line numbers refer to the decoded UTF-8 input, and not to the UTF-16 bytes.
+// The invalid UTF-16 sequences are decoded as U+FFFD.
+func WithAutoUTF16() Option {
+	return func(s *scanner) {
+		s.autoUTF16 = true
+	}
+}
+
+// utf16BOM returns the length of the UTF-16 BOM at the start of data (0 if none)
+// and true if it is big endian.
+func utf16BOM(data []byte) (n int, bigEndian bool) {
+	switch {
+	case bytes.HasPrefix(data, utf16LEBOM):
+		return 2, false
+	case bytes.HasPrefix(data, utf16BEBOM):
+		return 2, true
+	}
+	return 0, false
+}
+
+// decodeUTF16 returns the reader r decoded to UTF-8 if it starts with a UTF-16 BOM (see WithAutoUTF16).
+func decodeUTF16(r io.Reader) io.Reader {
+	peek := make([]byte, 2)
+	// a read error is not reported here, it is expected to happen again on the next read
+	n, _ := io.ReadFull(r, peek)
+	bom, big := utf16BOM(peek[:n])
+	if bom == 0 {
+		return io.MultiReader(bytes.NewReader(peek[:n]), r)
+	}
+	return &utf16Reader{r: r, big: big, buf: make([]byte, 4096)}
+}
+
+// utf16Reader decodes an UTF-16 reader to UTF-8.
+type utf16Reader struct {
+	r   io.Reader // the UTF-16 input (without BOM)
+	big bool      // true if the input is big endian
+	buf []byte    // buffer used to read from r
+	in  []byte    // the bytes read but not decoded yet (an incomplete code unit or surrogate pair)
+	out []byte    // the decoded bytes not returned yet
+	err error     // the error returned by r
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.out) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		n, err := u.r.Read(u.buf)
+		u.in = append(u.in, u.buf[:n]...)
+		u.err = err
+		u.out = appendUTF16(u.out[:0], &u.in, u.big, err != nil)
+	}
+	n := copy(p, u.out)
+	u.out = u.out[n:]
+	return n, nil
+}
+
+// appendUTF16 appends to dst the UTF-8 encoding of the UTF-16 data in *in,
+// and keeps in *in the bytes that could not be decoded yet (unless final is true).
+func appendUTF16(dst []byte, in *[]byte, big bool, final bool) []byte {
+	data := *in
+	unit := func(i int) rune {
+		if big {
+			return rune(data[i])<<8 | rune(data[i+1])
+		}
+		return rune(data[i+1])<<8 | rune(data[i])
+	}
+	i := 0
+	for i+2 <= len(data) {
+		r := unit(i)
+		if r >= 0xD800 && r < 0xDC00 {
+			// a high surrogate should be followed by a low one
+			if i+4 > len(data) {
+				if !final {
+					break
+				}
+				r, i = utf8.RuneError, i+2
+			} else if r = utf16.DecodeRune(r, unit(i+2)); r == utf8.RuneError {
+				i += 2
+			} else {
+				i += 4
+			}
+		} else {
+			// a lone low surrogate is encoded as U+FFFD by utf8.AppendRune
+			i += 2
+		}
+		dst = utf8.AppendRune(dst, r)
+	}
+	if final && i < len(data) {
+		// an odd byte at the end
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+		i = len(data)
+	}
+	*in = append(data[:0], data[i:]...)
+	return dst
+}
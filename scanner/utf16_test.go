@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// encodeUTF16 returns s encoded in UTF-16 with a BOM.
+func encodeUTF16(s string, big bool) []byte {
+	b := []byte{0xFF, 0xFE}
+	if big {
+		b = []byte{0xFE, 0xFF}
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		if big {
+			b = append(b, byte(u>>8), byte(u))
+		} else {
+			b = append(b, byte(u), byte(u>>8))
+		}
+	}
+	return b
+}
+
+func TestAutoUTF16(t *testing.T) {
+	text := "a,b\n\U0001F600,é\n"
+	expected := "a@0|b@2|\U0001F600@4|é@9"
+	data := [][]byte{
+		[]byte(text),
+		encodeUTF16(text, false),
+		encodeUTF16(text, true),
+	}
+	for _, d := range data {
+		for _, sc := range []Scanner{
+			New(bytes.NewReader(d), WithAutoUTF16()),
+			New(iotest.OneByteReader(bytes.NewReader(d)), WithAutoUTF16()),
+			NewBytes(d, WithAutoUTF16()),
+		} {
+			got := []string{}
+			for sc.Scan() {
+				got = append(got, string(sc.Bytes())+"@"+fmt.Sprint(sc.Offset()))
+			}
+			if strings.Join(got, "|") != expected || sc.Err() != nil {
+				t.Errorf("for %q expected %q, got %q (error: %v)", d, expected, strings.Join(got, "|"), sc.Err())
+			}
+		}
+	}
+}
+
+func TestAppendUTF16(t *testing.T) {
+	data := []struct {
+		in       []byte
+		final    bool
+		expected string
+		rest     int
+	}{
+		{[]byte{'a', 0, 'b'}, false, "a", 1},
+		{[]byte{'a', 0, 'b'}, true, "a\uFFFD", 0},
+		{[]byte{0x3D, 0xD8}, false, "", 2},               // high surrogate waiting for the low one
+		{[]byte{0x3D, 0xD8}, true, "\uFFFD", 0},          // lone high surrogate
+		{[]byte{0x3D, 0xD8, 'a', 0}, true, "\uFFFDa", 0}, // high surrogate not followed by a low one
+		{[]byte{0x00, 0xDE, 'a', 0}, true, "\uFFFDa", 0}, // lone low surrogate
+	}
+	for _, d := range data {
+		in := append([]byte{}, d.in...)
+		got := appendUTF16(nil, &in, false, d.final)
+		if string(got) != d.expected || len(in) != d.rest {
+			t.Errorf("for %v expected %q and %d bytes left, got %q and %d bytes left", d.in, d.expected, d.rest, got, len(in))
+		}
+	}
+}
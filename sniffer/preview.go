@@ -0,0 +1,137 @@
+package sniffer
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/kpym/csv/scanner"
+)
+
+// previewSample is the size of the sample sniffed by Preview.
+const previewSample = DefaultSampleBytes
+
+// Preview reads at most maxRows data rows from r with the parameters guessed from its beginning (see NewSniffed),
+// infers the type of each column and detects the header.
+// Only the needed part of r is read. Comments and empty lines are skipped.
+// If no header is detected, header is nil and the first row is in rows.
+// The first row is a header if some of its fields does not have the type of the other values of its column,
+// or if all the columns are strings and its fields are distinct, not empty and not repeated in their column.
+// The types are inferred from the values of the rows (without the header), ignoring the empty values.
+func Preview(r io.Reader, maxRows int) (header []string, rows [][]string, types []scanner.ColumnType, params *Parameters, err error) {
+	sc, params, err := NewSniffed(r, previewSample)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	// read one more row for the eventual header
+	for len(rows) <= maxRows {
+		var row []string
+		if err = sc.ReadInto(&row); err != nil {
+			break
+		}
+		rows = append(rows, row)
+	}
+	if err != io.EOF && err != nil {
+		return nil, nil, nil, params, err
+	}
+	if len(rows) == 0 {
+		return nil, nil, nil, params, nil
+	}
+	types = inferTypes(rows[1:])
+	if isHeader(rows[0], rows[1:], types) {
+		header, rows = rows[0], rows[1:]
+	} else {
+		rows = rows[:min(len(rows), maxRows)]
+		types = inferTypes(rows)
+	}
+	return header, rows, types, params, nil
+}
+
+// inferTypes returns the type of each column of rows (see inferType).
+func inferTypes(rows [][]string) []scanner.ColumnType {
+	n := 0
+	for _, row := range rows {
+		n = max(n, len(row))
+	}
+	types := make([]scanner.ColumnType, n)
+	values := make([]string, 0, len(rows))
+	for i := range types {
+		values = values[:0]
+		for _, row := range rows {
+			if i < len(row) {
+				values = append(values, row[i])
+			}
+		}
+		types[i] = inferType(values)
+	}
+	return types
+}
+
+// inferType returns the narrowest type (int, float, bool or string) of the not empty values.
+// If all the values are empty, TypeString is returned.
+func inferType(values []string) scanner.ColumnType {
+	found := false
+	for _, t := range []scanner.ColumnType{scanner.TypeInt, scanner.TypeFloat, scanner.TypeBool} {
+		ok := true
+		for _, v := range values {
+			if v == "" {
+				continue
+			}
+			found = true
+			if !hasType(v, t) {
+				ok = false
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		if ok {
+			return t
+		}
+	}
+	return scanner.TypeString
+}
+
+// hasType returns true if v could be parsed as a value of type t.
+func hasType(v string, t scanner.ColumnType) bool {
+	var err error
+	switch t {
+	case scanner.TypeInt:
+		_, err = strconv.ParseInt(v, 10, 64)
+	case scanner.TypeFloat:
+		_, err = strconv.ParseFloat(v, 64)
+	case scanner.TypeBool:
+		_, err = strconv.ParseBool(v)
+	}
+	return err == nil
+}
+
+// isHeader returns true if row looks like the header of rows with the given column types.
+func isHeader(row []string, rows [][]string, types []scanner.ColumnType) bool {
+	allStrings := true
+	for i, t := range types {
+		if t == scanner.TypeString {
+			continue
+		}
+		allStrings = false
+		if i < len(row) && row[i] != "" && !hasType(row[i], t) {
+			return true
+		}
+	}
+	if !allStrings || len(types) == 0 {
+		return false
+	}
+	seen := make(map[string]bool, len(row))
+	for i, f := range row {
+		if f == "" || seen[f] {
+			return false
+		}
+		seen[f] = true
+		for _, r := range rows {
+			if i < len(r) && r[i] == f {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package sniffer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		in      string
+		maxRows int
+		want    string
+	}{
+		{"", 2, "[] [] []"},
+		{"name;count;price;ok\nx;1;1.5;true\ny;2;2;false\nz;3;;true\n", 2,
+			"[name count price ok] [[x 1 1.5 true] [y 2 2 false]] [string int float bool]"},
+		{"1,2\n3,4\n5,6\n", 2, "[] [[1 2] [3 4]] [int int]"},
+		{"# comment\nname,city\nbob,paris\nalice,rome\n", 5, "[name city] [[bob paris] [alice rome]] [string string]"},
+		{"bob,paris\nbob,rome\n", 5, "[] [[bob paris] [bob rome]] [string string]"},
+	}
+	for _, test := range tests {
+		header, rows, types, _, err := Preview(strings.NewReader(test.in), test.maxRows)
+		if got := fmt.Sprint(header, rows, types); got != test.want || err != nil {
+			t.Errorf("Preview(%q) = %s (error: %v), want %s", test.in, got, err, test.want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"sort"
+	"strconv"
 
 	"github.com/kpym/csv/scanner"
 )
@@ -31,6 +32,34 @@ func (p *Parameters) NewScanner(r io.Reader) scanner.Scanner {
 	)
 }
 
+// DefaultSampleBytes is the size of the sample read by NewSniffed when sampleBytes is not positive.
+const DefaultSampleBytes = 64 << 10
+
+// NewSniffed reads a sample of at most sampleBytes bytes (DefaultSampleBytes if sampleBytes <= 0) from r, guesses the parameters from it
+// and returns a scanner configured with them that reads the sample followed by the rest of r.
+// The parameters are nil if they can't be guessed (and the scanner uses the default options).
+// The error is not nil only if the sample could not be read.
+func NewSniffed(r io.Reader, sampleBytes int, opts ...Option) (scanner.Scanner, *Parameters, error) {
+	if sampleBytes <= 0 {
+		sampleBytes = DefaultSampleBytes
+	}
+	sample := make([]byte, sampleBytes)
+	n, err := io.ReadFull(r, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	sample = sample[:n]
+	// do not sniff the last line if it is truncated
+	data := sample
+	if n == sampleBytes {
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+	p, _ := NewSniffer(data, opts...).GuessParameters()
+	return p.NewScanner(io.MultiReader(bytes.NewReader(sample), r)), p, nil
+}
+
 // SepQuoteScore is a pair of separator and quote character with a score.
 // The score is used to determine the best pair.
 // Ordered slice of SepQuoteScore are generated by Sniffer.Sniff().
@@ -57,6 +86,12 @@ type Sniffer struct {
 	comments [][]byte
 	// strict mode
 	strict bool
+	// number of goroutines used to collect the stats
+	concurrency int
+	// custom scoring function added to the score of each pair of separator and quote
+	scorer func(data []byte, sep, quote byte) int
+	// multiplier of the scores collected on the first line (1 if not weighted)
+	firstLineWeight int
 }
 
 // Options for Sniffer.
@@ -78,13 +113,22 @@ func (s *Sniffer) Options(opts ...Option) {
 	}
 }
 
-// DefaultOptions are the default options for a Sniffer.
+// DefaultOptions are the default options for a Sniffer, applied by NewSniffer before the custom ones.
+// The options are applied in order, so the later options override the earlier ones.
+// Use Defaults to get a copy of these options with some overrides.
 var DefaultOptions = []Option{
 	PossibleSeparators([]byte{',', ';', '\t', '|', '&'}),
 	PossibleQuotes([]byte{'"', '\'', '`'}),
 	PossibleEscapes([]byte{EscapeSameAsQuote, '\\'}),
 	PossibleComments([][]byte{{'#'}, {'/', '/'}}),
 	Strict(false),
+	Concurrency(1),
+}
+
+// Defaults returns a new slice with the DefaultOptions followed by the overrides,
+// that could be passed to NewSniffer or modified without changing DefaultOptions.
+func Defaults(overrides ...Option) []Option {
+	return append(append([]Option(nil), DefaultOptions...), overrides...)
 }
 
 // duplicate returns a copy of the byte slice.
@@ -139,6 +183,34 @@ func Strict(strict bool) Option {
 	}
 }
 
+// FirstLineWeight multiplies by multiplier the scores of the separators and quotes found on the first line,
+// that is in general a clean header. It helps if the data rows are noisy.
+// If multiplier <= 1 the first line is scored as the other lines.
+func FirstLineWeight(multiplier int) Option {
+	return func(s *Sniffer) {
+		s.firstLineWeight = multiplier
+	}
+}
+
+// Concurrency sets the number of goroutines used to collect the separator and quote stats.
+// The data is split in n chunks on line boundaries that are scored in parallel.
+// If n <= 1 the stats are collected sequentially.
+// This is useful only for very large samples.
+func Concurrency(n int) Option {
+	return func(s *Sniffer) {
+		s.concurrency = n
+	}
+}
+
+// WithScorer sets a custom scoring function.
+// Its result for each pair of separator and quote characters is added to the computed score
+// (see GuessSepQuoteScore) before sorting.
+func WithScorer(fn func(data []byte, sep, quote byte) int) Option {
+	return func(s *Sniffer) {
+		s.scorer = fn
+	}
+}
+
 // GuessParameters returns the most probable parameters.
 // In strict mode, it will return nil if it can't verify the parameters.
 func (s *Sniffer) GuessParameters() (p *Parameters, verified bool) {
@@ -274,17 +346,59 @@ func (s *Sniffer) GuessSepQuoteScore() []SepQuoteScore {
 	for quote, qvalue := range t.quotes {
 		for sep, svalue := range t.seps {
 			value := qvalue + svalue + t.pairs[sqPair{sep, quote}]
+			if s.scorer != nil {
+				value += s.scorer(s.data, sep, quote)
+			}
 			sqs = append(sqs, SepQuoteScore{sep, quote, value})
 		}
 	}
-	// sort by score
+	// sort by score, the ties are ordered as in the possible separators and quotes
 	sort.Slice(sqs, func(i, j int) bool {
-		return sqs[i].Score > sqs[j].Score
+		if sqs[i].Score != sqs[j].Score {
+			return sqs[i].Score > sqs[j].Score
+		}
+		if si, sj := indexOf(s.seps, sqs[i].Sep), indexOf(s.seps, sqs[j].Sep); si != sj {
+			return si < sj
+		}
+		return indexOf(s.quotes, sqs[i].Quote) < indexOf(s.quotes, sqs[j].Quote)
 	})
 
 	return sqs
 }
 
+// MergeSepQuoteScores merges the scores returned by GuessSepQuoteScore for two samples of the same file
+// (like its head and its tail): the scores of the same pairs of separator and quote are summed.
+// As the scores grow with the size of the samples, the larger sample has more weight
+// (multiply the scores of a sample to give it more confidence).
+// The merged scores are sorted by decreasing score, the ties being ordered as in a followed by b.
+func MergeSepQuoteScores(a, b []SepQuoteScore) []SepQuoteScore {
+	merged := make([]SepQuoteScore, 0, len(a)+len(b))
+	index := make(map[sqPair]int, len(a)+len(b))
+	for _, sqs := range [][]SepQuoteScore{a, b} {
+		for _, sq := range sqs {
+			p := sqPair{sq.Sep, sq.Quote}
+			if i, ok := index[p]; ok {
+				merged[i].Score += sq.Score
+				continue
+			}
+			index[p] = len(merged)
+			merged = append(merged, sq)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Score > merged[j].Score
+	})
+	return merged
+}
+
+// indexOf returns the index of c in set, or len(set) if c is not in set.
+func indexOf(set []byte, c byte) int {
+	if i := bytes.IndexByte(set, c); i >= 0 {
+		return i
+	}
+	return len(set)
+}
+
 // GuessEscape returns the most probable escape character for the given quote character.
 // If no possible escape character is given, returns 0 (no-escape)
 // If no escape character is found and the mode is strict, 0 is returned,
@@ -313,12 +427,13 @@ func (s *Sniffer) GuessEscape(quote byte) byte {
 		}
 	}
 	// find the escape character with the highest score
+	// (the first possible escape character wins the ties)
 	var escape byte = eq(s.escapes[0], quote)
 	var max int
-	for c, s := range score {
-		if s > max {
-			max = s
-			escape = c
+	for _, c := range s.escapes {
+		if sc := score[eq(c, quote)]; sc > max {
+			max = sc
+			escape = eq(c, quote)
 		}
 	}
 	if max == 0 && s.strict {
@@ -329,6 +444,20 @@ func (s *Sniffer) GuessEscape(quote byte) byte {
 	return escape
 }
 
+// GuessQuoting returns the most probable quote and escape characters,
+// and doubling is true if the quotes are escaped by doubling them (like "").
+// The escape character is guessed by GuessEscape, where the quote-quote and escape-quote occurrences are counted.
+// The parameters returned by GuessParameters contain the same information (Escape == Quote for doubling),
+// so Parameters.NewScanner configures the escape character accordingly.
+func (s *Sniffer) GuessQuoting() (quote byte, escape byte, doubling bool) {
+	_, quote = s.BestSepQuote()
+	if quote == 0 {
+		return 0, 0, false
+	}
+	escape = s.GuessEscape(quote)
+	return quote, escape, escape == quote
+}
+
 // eq normalizes the escape character,
 // ie replace EscapeSameAsQuote by the quote character.
 func eq(escape, quote byte) byte {
@@ -383,3 +512,203 @@ func checkRowsLen(data []byte, p *Parameters) bool {
 	// only one row or only one column (no separator) => can't verify
 	return false
 }
+
+// GuessFooter returns true if the last row of the data looks like a footer (like a totals row).
+// The last row is a footer if its number of columns differs from the most frequent one,
+// or if its first field is not a number while the first fields of the other data rows are.
+// The data should contain the end of the file and at least three rows (including the header),
+// else false is returned.
+func (s *Sniffer) GuessFooter() bool {
+	p, _ := s.GuessParameters()
+	if p == nil {
+		return false
+	}
+	rows := scanRows(s.data, p)
+	if len(rows) < 3 {
+		return false
+	}
+	last := rows[len(rows)-1]
+	rows = rows[:len(rows)-1]
+	// the most frequent number of columns
+	count := make(map[int]int)
+	modal := 0
+	for _, row := range rows {
+		count[len(row)]++
+		if count[len(row)] > count[modal] {
+			modal = len(row)
+		}
+	}
+	if len(last) != modal {
+		return true
+	}
+	// a label in the first column while the data rows (without the header) are numeric
+	for _, row := range rows[1:] {
+		if !isNumber(row[0]) {
+			return false
+		}
+	}
+	return !isNumber(last[0])
+}
+
+// HasTrailingNewline returns true if the data ends with a line break ("\n" or "\r\n").
+// The data should contain the end of the file, so that a writer could reproduce it.
+// For empty data, false is returned.
+func (s *Sniffer) HasTrailingNewline() bool {
+	return len(s.data) > 0 && s.data[len(s.data)-1] == '\n'
+}
+
+// IsProbablyFixedWidth returns true if the data looks like fixed-width columns, without a real separator.
+// This is the case if no possible separator splits all the data rows in the same number (at least 2) of fields,
+// while the lines (without the comments and the empty lines) have the same length (in runes)
+// and are aligned: at some positions all the lines have a space, followed by a non-space in at least one line.
+// At least two lines are needed, and the last line is ignored if it is not terminated (probably truncated).
+func (s *Sniffer) IsProbablyFixedWidth() bool {
+	lines := fixedWidthLines(s.data, s.GuessComment())
+	if len(lines) < 2 {
+		return false
+	}
+	// a real separator splits all the rows in the same number of fields
+	if p, _ := s.GuessParameters(); p != nil && p.Separator != 0 {
+		rows := scanRows(s.data, p)
+		same := len(rows) > 0 && len(rows[0]) > 1
+		for _, row := range rows {
+			same = same && len(row) == len(rows[0])
+		}
+		if same {
+			return false
+		}
+	}
+	// the lines have the same length
+	width := len(lines[0])
+	for _, line := range lines {
+		if len(line) != width {
+			return false
+		}
+	}
+	// the columns are separated by spaces at the same positions
+	for i := 1; i < width-1; i++ {
+		gap, next := true, false
+		for _, line := range lines {
+			gap = gap && line[i] == ' '
+			next = next || line[i+1] != ' '
+		}
+		if gap && next {
+			return true
+		}
+	}
+	return false
+}
+
+// fixedWidthLines returns the lines of data as runes (without the line breaks),
+// except the empty lines, the comments and the last line if it is not terminated.
+func fixedWidthLines(data []byte, comment []byte) [][]rune {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	var lines [][]rune
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+		if len(bytes.TrimSpace(line)) == 0 || (len(comment) > 0 && bytes.HasPrefix(line, comment)) {
+			continue
+		}
+		lines = append(lines, []rune(string(line)))
+	}
+	return lines
+}
+
+// GuessQuoteConsistency returns the fraction (between 0 and 1) of the rows where the quotes are consistent,
+// ie every field containing the quote character is quoted (and the quote is closed).
+// The rows are scanned with the parameters returned by GuessParameters, comments and empty lines are skipped.
+// A low value is a sign that the parameters are wrong or that the data is malformed.
+// If there are no rows or no quote character, 1 is returned.
+func (s *Sniffer) GuessQuoteConsistency() float64 {
+	p, _ := s.GuessParameters()
+	if p == nil || p.Quote == 0 {
+		return 1
+	}
+	scan := p.NewScanner(bytes.NewReader(s.data))
+	rows, consistent := 0, 0
+	ok := true
+	for scan.Scan() {
+		if scan.IsComment() || scan.IsEmptyLine() {
+			continue
+		}
+		if scan.AtRowStart() {
+			rows++
+			ok = true
+		}
+		if scan.IsQuoted() {
+			_, closeAt := scan.QuoteSpans()
+			ok = ok && closeAt != -1
+		} else {
+			ok = ok && bytes.IndexByte(scan.Bytes(), p.Quote) == -1
+		}
+		if scan.AtRowEnd() && ok {
+			consistent++
+		}
+	}
+	if rows == 0 {
+		return 1
+	}
+	return float64(consistent) / float64(rows)
+}
+
+// EstimateRows returns an estimation of the number of rows (including comments and empty lines) in data,
+// that could be used to preallocate the slice of rows.
+// The newlines inside the quotes (p.Quote, escaped by p.Escape) are not counted.
+// It is fast, but not exact, as it does not check where the quotes are (like a quote in a comment).
+// If p is nil, the default quote '"' is used.
+func EstimateRows(data []byte, p *Parameters) int {
+	quote, escape := byte('"'), byte('"')
+	if p != nil {
+		quote, escape = p.Quote, p.Escape
+	}
+	if quote == 0 {
+		rows := bytes.Count(data, []byte{'\n'})
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			rows++
+		}
+		return rows
+	}
+	rows := 0
+	inQuote := false
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; {
+		case inQuote && c == escape && escape != quote:
+			// skip the escaped character
+			i++
+		case c == quote:
+			// an escaped quote by doubling closes and reopens the quotes
+			inQuote = !inQuote
+		case c == '\n' && !inQuote:
+			rows++
+		}
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		rows++
+	}
+	return rows
+}
+
+// isNumber returns true if the field (without surrounding spaces) is a number.
+func isNumber(field []byte) bool {
+	_, err := strconv.ParseFloat(string(bytes.TrimSpace(field)), 64)
+	return err == nil
+}
+
+// scanRows returns the rows of data scanned with the parameters p.
+// Comments and empty lines are skipped.
+func scanRows(data []byte, p *Parameters) [][][]byte {
+	scan := p.NewScanner(bytes.NewReader(data))
+	var rows [][][]byte
+	for scan.Scan() {
+		if scan.IsComment() || scan.IsEmptyLine() {
+			continue
+		}
+		if scan.AtRowStart() || len(rows) == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], duplicate(scan.Bytes()))
+	}
+	return rows
+}
@@ -2,6 +2,11 @@
 // where the stats about (<separator>, <quote>) are collected.
 package sniffer
 
+import (
+	"bytes"
+	"sync"
+)
+
 // The bonus are used to attribute a score to a pair of separator and quote character.
 // When a character is found a minimal score of 1 is attributed to it.
 const (
@@ -39,12 +44,36 @@ func (s *Sniffer) newTempStats() *tempStats {
 	// create a new tempStats
 	t := initTempStats(s)
 	// collect stats
-	t.collectTempStats(s.data)
+	if s.concurrency > 1 {
+		t.collectTempStatsConcurrently(s, s.data, s.concurrency)
+	} else {
+		t.collectTempStats(s.data)
+	}
+	// the first line is already scored once
+	if s.firstLineWeight > 1 {
+		first := initTempStats(s)
+		first.collectTempStats(firstLine(s.data))
+		first.scale(s.firstLineWeight - 1)
+		t.merge(first)
+	}
 	// clean maps
 	t.cleanTempStats()
 	return t
 }
 
+// Stats returns a copy of the scores collected by the sniffer,
+// for the separators, the quotes and the pairs [separator, quote].
+// The characters with no score are not present.
+// It is useful to understand why a separator or a quote is chosen by GuessSepQuoteScore.
+func (s *Sniffer) Stats() (seps map[byte]int, quotes map[byte]int, pairs map[[2]byte]int) {
+	t := s.newTempStats()
+	pairs = make(map[[2]byte]int, len(t.pairs))
+	for p, v := range t.pairs {
+		pairs[[2]byte{p.sep, p.quote}] = v
+	}
+	return t.seps, t.quotes, pairs
+}
+
 // initTempStats returns a new zeroed tempStats.
 func initTempStats(s *Sniffer) *tempStats {
 	// create a new tempStats
@@ -69,6 +98,12 @@ func initTempStats(s *Sniffer) *tempStats {
 // A score is attributed to the single characters and eventually to the pair of separator and quote character.
 // The scores are added to the maps Sniffer.seps, Sniffer.quotes and Sniffer.pairs.
 func (t *tempStats) collectTempStats(data []byte) {
+	t.collectChunkStats(data, true)
+}
+
+// collectChunkStats is collectTempStats for a chunk of the data starting at a line start.
+// The bonuses for the first separator and quote are attributed only if first is true.
+func (t *tempStats) collectChunkStats(data []byte, first bool) {
 	if len(data) == 0 {
 		return
 	}
@@ -77,8 +112,8 @@ func (t *tempStats) collectTempStats(data []byte) {
 	const newline = byte('\n')
 
 	// useed to attribute the firstBonus
-	isFirstSep := true
-	isFirstQuote := true
+	isFirstSep := first
+	isFirstQuote := first
 
 	// used to attribute the noSpaceBonus
 	prevChar := newline   // we are at the beginning of a new line
@@ -91,7 +126,7 @@ func (t *tempStats) collectTempStats(data []byte) {
 	prevNonSpaceIsQuote := false
 
 	// check if the very first character is a quote character
-	if c := data[0]; t.isQuoteChar(c) {
+	if c := data[0]; first && t.isQuoteChar(c) {
 		t.quotes[c] += veryFirstQuoteBonus
 	}
 	// loop over the data byte by byte, scanning for separators and quote characters
@@ -166,6 +201,85 @@ func (t *tempStats) collectTempStats(data []byte) {
 	}
 }
 
+// collectTempStatsConcurrently is collectTempStats but the data is split in n chunks
+// (on line boundaries) that are scored in parallel and then merged.
+// As the scoring depends only on the current line, the result is the same as for collectTempStats,
+// except for the first separator and first quote bonuses, that are given only in the first chunk:
+// they are lost if the first chunk contains no separator (or no quote after a separator).
+func (t *tempStats) collectTempStatsConcurrently(s *Sniffer, data []byte, n int) {
+	chunks := splitLines(data, n)
+	stats := make([]*tempStats, len(chunks))
+	var wg sync.WaitGroup
+	for i := range chunks {
+		stats[i] = initTempStats(s)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stats[i].collectChunkStats(chunks[i], i == 0)
+		}(i)
+	}
+	wg.Wait()
+	for _, st := range stats {
+		t.merge(st)
+	}
+}
+
+// splitLines splits the data in at most n chunks of similar size.
+// Each chunk, except the last one, ends with a newline.
+func splitLines(data []byte, n int) [][]byte {
+	chunks := make([][]byte, 0, n)
+	size := len(data)/n + 1
+	for len(data) > 0 {
+		end := size
+		if end >= len(data) {
+			chunks = append(chunks, data)
+			break
+		}
+		if i := bytes.IndexByte(data[end-1:], '\n'); i >= 0 {
+			end += i
+		} else {
+			end = len(data)
+		}
+		chunks = append(chunks, data[:end])
+		data = data[end:]
+	}
+	return chunks
+}
+
+// merge adds the scores of o to t.
+func (t *tempStats) merge(o *tempStats) {
+	for c, v := range o.seps {
+		t.seps[c] += v
+	}
+	for c, v := range o.quotes {
+		t.quotes[c] += v
+	}
+	for p, v := range o.pairs {
+		t.pairs[p] += v
+	}
+}
+
+// scale multiplies all the scores of t by m.
+func (t *tempStats) scale(m int) {
+	for c := range t.seps {
+		t.seps[c] *= m
+	}
+	for c := range t.quotes {
+		t.quotes[c] *= m
+	}
+	for p := range t.pairs {
+		t.pairs[p] *= m
+	}
+}
+
+// firstLine returns the first line of data (including the newline).
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i+1]
+	}
+	return data
+}
+
 // isSepChar returns true if c is a separator character.
 // It is used by stats()
 func (t *tempStats) isSepChar(c byte) bool {
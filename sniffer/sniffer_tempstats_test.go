@@ -1,6 +1,10 @@
 package sniffer
 
 import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
 	"testing"
 )
 
@@ -146,3 +150,84 @@ func TestCleanTempStats(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitLines(t *testing.T) {
+	data := []struct {
+		in  string
+		n   int
+		out []string
+	}{
+		{"", 4, []string{}},
+		{"a,b\nc,d\n", 1, []string{"a,b\nc,d\n"}},
+		{"a,b\nc,d\n", 2, []string{"a,b\nc,d\n"}},
+		{"a,b\nc,d\ne,f\ng,h", 4, []string{"a,b\n", "c,d\n", "e,f\n", "g,h"}},
+		{"abcdefgh\ni", 4, []string{"abcdefgh\n", "i"}},
+	}
+	for _, d := range data {
+		got := splitLines([]byte(d.in), d.n)
+		if len(got) != len(d.out) {
+			t.Errorf("for %q expected %q, got %q", d.in, d.out, got)
+			continue
+		}
+		for i := range got {
+			if string(got[i]) != d.out[i] {
+				t.Errorf("for %q expected %q, got %q", d.in, d.out, got)
+				break
+			}
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	data := [][]byte{
+		nil,
+		[]byte(`"a","b";"c"`),
+		[]byte("a; \"b|c\" ; \" d' \";e\n1,1;2,2;3,3;4,4\n\"x\";'y';z\n"),
+		bytes.Repeat([]byte("\"a\",'b';c|d\n"), 100),
+	}
+	for _, d := range data {
+		seq := NewSniffer(d).newTempStats()
+		for _, n := range []int{2, 3, 8} {
+			par := NewSniffer(d, Concurrency(n)).newTempStats()
+			if !reflect.DeepEqual(seq, par) {
+				t.Errorf("for %q and concurrency %d expected %v, got %v", d, n, seq, par)
+			}
+		}
+	}
+}
+
+// BenchmarkConcurrency compares the sequential (concurrency-1) and the parallel collection of the stats.
+// The speedup of the parallel collection depends on the number of CPUs (see -cpu).
+func BenchmarkConcurrency(b *testing.B) {
+	// ~50MB sample
+	largeSample := bytes.Repeat([]byte("\"a\",b;'c',1.5|\"d, e\"\n"), 50<<20/22)
+	ns := []int{1, 2, 4, 8}
+	if c := runtime.NumCPU(); c > 8 {
+		ns = append(ns, c)
+	}
+	for _, n := range ns {
+		b.Run(fmt.Sprintf("concurrency-%d", n), func(b *testing.B) {
+			s := NewSniffer(largeSample, Concurrency(n))
+			b.SetBytes(int64(len(largeSample)))
+			for i := 0; i < b.N; i++ {
+				s.newTempStats()
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewSniffer([]byte(`"a","b";"c"`), PossibleSeparators([]byte{',', ';', '|'}), PossibleQuotes([]byte{'"', '\''}))
+	seps, quotes, pairs := s.Stats()
+	sq, _, _ := s.Stats()
+	sq[','] = -1
+	if len(seps) != 2 || seps[','] == 0 || seps[';'] == 0 || seps[','] < 0 {
+		t.Errorf("Expected scores for , and ;, got %v", seps)
+	}
+	if len(quotes) != 1 || quotes['"'] == 0 {
+		t.Errorf("Expected a score for \", got %v", quotes)
+	}
+	if len(pairs) != 2 || pairs[[2]byte{',', '"'}] == 0 || pairs[[2]byte{';', '"'}] == 0 {
+		t.Errorf("Expected scores for the pairs ,\" and ;\", got %v", pairs)
+	}
+}
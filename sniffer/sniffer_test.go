@@ -2,8 +2,11 @@ package sniffer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestGuessParametersNoData(t *testing.T) {
@@ -204,3 +207,257 @@ func TestGuessEscape(t *testing.T) {
 		}
 	}
 }
+
+func TestHasTrailingNewline(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{"", false},
+		{"\n", true},
+		{"a,b\n1,2", false},
+		{"a,b\n1,2\n", true},
+		{"a,b\r\n1,2\r\n", true},
+		{"a,b\n1,2\r", false},
+	}
+	for _, test := range tests {
+		if got := NewSniffer([]byte(test.data)).HasTrailingNewline(); got != test.want {
+			t.Errorf("HasTrailingNewline(%q) = %t, want %t", test.data, got, test.want)
+		}
+	}
+}
+
+func TestGuessFooter(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want bool
+	}{
+		{[]byte(""), false},
+		{[]byte("a,b,c\n1,2,3\n"), false},
+		{[]byte("a,b,c\n1,2\n"), false},
+		{[]byte("a,b,c\n1,2,3\n4,5,6\n"), false},
+		{[]byte("a,b,c\n1,2,3\n4,5,6\n9,7\n"), true},
+		{[]byte("a,b,c\n1,2,3\n4,5,6\nTotal,7,9\n"), true},
+		{[]byte("a,b,c\nx,2,3\ny,5,6\nTotal,7,9\n"), false},
+		{[]byte("# comment\na,b,c\n1,2,3\n\n4,5,6\n# end\n"), false},
+	}
+	for _, test := range tests {
+		s := NewSniffer(test.data)
+		if got := s.GuessFooter(); got != test.want {
+			t.Errorf("GuessFooter(%q) = %t, want %t", test.data, got, test.want)
+		}
+	}
+}
+
+func TestIsProbablyFixedWidth(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want bool
+	}{
+		{[]byte(""), false},
+		{[]byte("name  age\n"), false},
+		{[]byte("name  age city \njohn  25  paris\nmary  31  rome \n"), true},
+		{[]byte("# people\nname  age\n\njohn  25 \nmary  31 \npeter 4"), true},
+		{[]byte("name  äge\njöhn  25 \n"), true},
+		{[]byte("name  age\njohn  25\n"), false},
+		{[]byte("name,age\njohn,25 \n"), false},
+		{[]byte("a b,c d\ne f,g h\n"), false},
+		{[]byte("abcdef\nghijkl\n"), false},
+	}
+	for _, test := range tests {
+		s := NewSniffer(test.data)
+		if got := s.IsProbablyFixedWidth(); got != test.want {
+			t.Errorf("IsProbablyFixedWidth(%q) = %t, want %t", test.data, got, test.want)
+		}
+	}
+}
+
+func TestGuessQuoteConsistency(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want float64
+	}{
+		{[]byte(""), 1},
+		{[]byte("a,b,c\n1,2,3\n"), 1},
+		{[]byte("a,\"b,c\"\n\"1\",2\n"), 1},
+		{[]byte("a,\"b\",c\n1,2\"x,3\n4,5,6\n7,\"8\",9\n"), 0.75},
+		{[]byte("# c\"\na,\"b\"\n\n1,\"2\n"), 0.5},
+	}
+	for _, test := range tests {
+		s := NewSniffer(test.data)
+		if got := s.GuessQuoteConsistency(); got != test.want {
+			t.Errorf("GuessQuoteConsistency(%q) = %v, want %v", test.data, got, test.want)
+		}
+	}
+}
+
+func TestGuessQuoting(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		quote    byte
+		escape   byte
+		doubling bool
+	}{
+		{[]byte(`a,"b""c""","d"`), '"', '"', true},
+		{[]byte(`a,"b\"c\"","d"`), '"', '\\', false},
+		{[]byte(`a;'b''c';d`), '\'', '\'', true},
+		{[]byte(`a,b,c`), '"', '"', true},
+	}
+	for _, test := range tests {
+		s := NewSniffer(test.data)
+		if q, e, d := s.GuessQuoting(); q != test.quote || e != test.escape || d != test.doubling {
+			t.Errorf("GuessQuoting(%q) = %q, %q, %t, want %q, %q, %t", test.data, q, e, d, test.quote, test.escape, test.doubling)
+		}
+	}
+	// strict mode without quotes
+	if q, e, d := NewSniffer([]byte(`a,b,c`), Strict(true)).GuessQuoting(); q != 0 || e != 0 || d {
+		t.Errorf("GuessQuoting(a,b,c) in strict mode = %q, %q, %t, want 0, 0, false", q, e, d)
+	}
+}
+
+func TestWithScorer(t *testing.T) {
+	data := []byte("a|b,c\nd|e,f\n")
+	seps := []byte{',', '|'}
+	if sep, _ := NewSniffer(data, PossibleSeparators(seps)).BestSepQuote(); sep != '|' {
+		t.Errorf("expected '|' without scorer, got %q", sep)
+	}
+	boostComma := func(data []byte, sep, quote byte) int {
+		if sep == ',' {
+			return 100
+		}
+		return 0
+	}
+	if sep, _ := NewSniffer(data, PossibleSeparators(seps), WithScorer(boostComma)).BestSepQuote(); sep != ',' {
+		t.Errorf("expected ',' with scorer, got %q", sep)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	n := len(DefaultOptions)
+	s := NewSniffer([]byte("a|b|c\n1|2|3\n"), Defaults(PossibleSeparators([]byte{','}), Strict(true))...)
+	if sep, _ := s.BestSepQuote(); sep != 0 || len(DefaultOptions) != n {
+		t.Errorf("expected no separator and %d default options, got %q and %d", n, sep, len(DefaultOptions))
+	}
+}
+
+func TestEstimateRows(t *testing.T) {
+	tests := []struct {
+		data []byte
+		p    *Parameters
+		want int
+	}{
+		{[]byte(""), nil, 0},
+		{[]byte("a,b\nc,d\n"), nil, 2},
+		{[]byte("a,b\nc,d"), nil, 2},
+		{[]byte("a,\"b\nc\"\n\"d\"\"\ne\",f\n"), nil, 2},
+		{[]byte("a;'b\\'\nc'\nd\n"), &Parameters{Separator: ';', Quote: '\'', Escape: '\\'}, 2},
+		{[]byte("a,\"b\nc\"\n"), &Parameters{Separator: ','}, 2},
+	}
+	for _, test := range tests {
+		if got := EstimateRows(test.data, test.p); got != test.want {
+			t.Errorf("EstimateRows(%q) = %d, want %d", test.data, got, test.want)
+		}
+	}
+}
+
+func TestGuessSepQuoteScoreTies(t *testing.T) {
+	data := []byte("a,b;c\n'd'\n\"e\"\n")
+	// a scorer that makes all the scores equal to 100
+	base := map[[2]byte]int{}
+	for _, sqs := range NewSniffer(data).GuessSepQuoteScore() {
+		base[[2]byte{sqs.Sep, sqs.Quote}] = sqs.Score
+	}
+	flat := WithScorer(func(_ []byte, sep, quote byte) int {
+		return 100 - base[[2]byte{sep, quote}]
+	})
+	for _, seps := range [][]byte{{',', ';'}, {';', ','}} {
+		for _, quotes := range [][]byte{{'"', '\''}, {'\'', '"'}} {
+			for i := 0; i < 20; i++ {
+				s := NewSniffer(data, PossibleSeparators(seps), PossibleQuotes(quotes), flat)
+				if sep, quote := s.BestSepQuote(); sep != seps[0] || quote != quotes[0] {
+					t.Fatalf("BestSepQuote(%q) with %q and %q = %q, %q, want %q, %q", data, seps, quotes, sep, quote, seps[0], quotes[0])
+				}
+			}
+		}
+	}
+}
+
+func TestMergeSepQuoteScores(t *testing.T) {
+	head := []SepQuoteScore{{';', '"', 20}, {',', '"', 8}, {';', '\'', 3}}
+	tail := []SepQuoteScore{{',', '"', 30}, {';', '"', 10}, {'|', '"', 3}}
+	got := MergeSepQuoteScores(head, tail)
+	expected := []SepQuoteScore{{',', '"', 38}, {';', '"', 30}, {';', '\'', 3}, {'|', '"', 3}}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := MergeSepQuoteScores(nil, nil); len(got) != 0 {
+		t.Errorf("expected no scores, got %v", got)
+	}
+	// the inputs are not modified
+	if head[0].Score != 20 || tail[0].Score != 30 {
+		t.Errorf("the inputs are modified: %v and %v", head, tail)
+	}
+}
+
+func TestGuessEscapeTies(t *testing.T) {
+	data := []byte(`a,"b""c\"d",e`)
+	for _, escapes := range [][]byte{{EscapeSameAsQuote, '\\'}, {'\\', EscapeSameAsQuote}} {
+		want := eq(escapes[0], '"')
+		for i := 0; i < 20; i++ {
+			s := NewSniffer(data, PossibleEscapes(escapes))
+			if got := s.GuessEscape('"'); got != want {
+				t.Fatalf("GuessEscape(%q) with %q = %q, want %q", data, escapes, got, want)
+			}
+		}
+	}
+}
+
+func TestNewSniffed(t *testing.T) {
+	in := "a;b;'c;d'\n1;2;3\n4;5;6\n7;8;9\n"
+	sc, p, err := NewSniffed(strings.NewReader(in), 20)
+	if err != nil || p == nil || p.Separator != ';' || p.Quote != '\'' {
+		t.Fatalf("expected ; and ' parameters, got %v (error: %v)", p, err)
+	}
+	got := []string{}
+	for sc.Scan() {
+		got = append(got, string(sc.Bytes()))
+	}
+	if expected := "a|b|c;d|1|2|3|4|5|6|7|8|9"; strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+	// a sample size that is not positive is DefaultSampleBytes
+	for _, size := range []int{0, -1} {
+		sc, p, err := NewSniffed(strings.NewReader(in), size)
+		if err != nil || p == nil || p.Separator != ';' || p.Quote != '\'' {
+			t.Fatalf("size %d: expected ; and ' parameters, got %v (error: %v)", size, p, err)
+		}
+		n := 0
+		for sc.Scan() {
+			n++
+		}
+		if n != 12 {
+			t.Errorf("size %d: expected 12 fields, got %d", size, n)
+		}
+	}
+	// the read error of the sample is returned
+	if _, _, err := NewSniffed(iotest.ErrReader(errors.New("read")), 20); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestFirstLineWeight(t *testing.T) {
+	data := []byte("name;tags\nx;a,b,c,d\ny;e,f,g,h\nz;i,j,k,l\n")
+	tests := []struct {
+		weight int
+		want   byte
+	}{
+		{0, ','},
+		{1, ','},
+		{3, ';'},
+	}
+	for _, test := range tests {
+		if sep, _ := NewSniffer(data, FirstLineWeight(test.weight)).BestSepQuote(); sep != test.want {
+			t.Errorf("FirstLineWeight(%d): expected %q, got %q", test.weight, test.want, sep)
+		}
+	}
+}
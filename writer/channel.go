@@ -0,0 +1,51 @@
+package writer
+
+import (
+	"context"
+)
+
+// WriteFrom writes the records received from ch (see WriteRecord) until ch is closed,
+// and returns the first error of the writer. See WriteFromContext.
+func (w *writer) WriteFrom(ch <-chan []string) error {
+	return w.WriteFromContext(context.Background(), ch)
+}
+
+// WriteFromContext writes the records received from ch (see WriteRecord) until ch is closed or ctx is done.
+// The written rows are flushed each time no record is ready, and at the end.
+// As ch is not read while a record is written, the producer is slowed down by a slow output.
+// It returns the first error of the writer, or the error of ctx if it is done before ch is closed.
+// The ctx is checked before each receive, so a record received from ch is always written.
+// The records are not drained from ch after an error.
+func (w *writer) WriteFromContext(ctx context.Context, ch <-chan []string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			w.Flush()
+			return err
+		}
+		var record []string
+		var ok bool
+		select {
+		case record, ok = <-ch:
+		default:
+			// no record is ready, flush the written ones before waiting
+			w.Flush()
+			if w.err != nil {
+				return w.err
+			}
+			select {
+			case record, ok = <-ch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if !ok {
+			break
+		}
+		w.WriteRecord(record)
+		if w.err != nil {
+			return w.err
+		}
+	}
+	w.Flush()
+	return w.err
+}
@@ -0,0 +1,58 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteFrom(t *testing.T) {
+	ch := make(chan []string)
+	go func() {
+		ch <- []string{"a", "b"}
+		ch <- []string{"c,d"}
+		close(ch)
+	}()
+	gotw := strings.Builder{}
+	w := New(&gotw)
+	if err := w.WriteFrom(ch); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if got, expected := gotw.String(), "a,b\n\"c,d\"\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	// the error of the writer is returned
+	ch = make(chan []string, 1)
+	ch <- []string{"a"}
+	close(ch)
+	w = New(&strings.Builder{}, WithQuote(','))
+	if err := w.WriteFrom(ch); err == nil {
+		t.Errorf("expected an error for an invalid quote")
+	}
+}
+
+func TestWriteFromContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan []string)
+	done := make(chan error)
+	gotw := strings.Builder{}
+	go func() {
+		done <- New(&gotw).WriteFromContext(ctx, ch)
+	}()
+	ch <- []string{"a"}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if got := gotw.String(); got != "a\n" {
+		t.Errorf("expected the first record to be flushed, got <%q>", got)
+	}
+	// nothing is received after ctx is done
+	ready := make(chan []string, 1)
+	ready <- []string{"b"}
+	gotw.Reset()
+	if err := New(&gotw).WriteFromContext(ctx, ready); !errors.Is(err, context.Canceled) || gotw.Len() != 0 || len(ready) != 1 {
+		t.Errorf("expected context.Canceled and nothing received, got %v, <%q> and %d records left", err, gotw.String(), len(ready))
+	}
+}
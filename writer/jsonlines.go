@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// JSONLinesWriter interface
+// It writes records as JSON objects (one per line) keyed by the header.
+type JSONLinesWriter interface {
+	// WriteRecord writes a record as a single line JSON object.
+	WriteRecord(record []string)
+
+	// Flush writes any buffered data to the underlying io.Writer.
+	Flush()
+
+	// Error reports any error that has occurred during a previous WriteRecord or Flush.
+	Error() error
+}
+
+type jsonLines struct {
+	bufw   *bufio.Writer // underlying buffered writer
+	err    error         // error encountered by the writer
+	header [][]byte      // JSON encoded keys
+	enc    *json.Encoder // encoder of the keys and values in buf (without HTML escaping)
+	buf    bytes.Buffer  // output of enc
+}
+
+// NewJSONLines returns a new JSONLinesWriter that writes to w.
+// The header contains the keys of the JSON objects.
+func NewJSONLines(w io.Writer, header []string) JSONLinesWriter {
+	jw := &jsonLines{
+		bufw:   bufio.NewWriter(w),
+		header: make([][]byte, len(header)),
+	}
+	jw.enc = json.NewEncoder(&jw.buf)
+	jw.enc.SetEscapeHTML(false)
+	for i, key := range header {
+		var k []byte
+		k, jw.err = jw.encode(key)
+		if jw.err != nil {
+			break
+		}
+		jw.header[i] = append([]byte(nil), k...)
+	}
+	return jw
+}
+
+// encode returns the JSON string of s, valid until the next call.
+// Unlike json.Marshal, the characters <, > and & are not escaped.
+func (w *jsonLines) encode(s string) ([]byte, error) {
+	w.buf.Reset()
+	if err := w.enc.Encode(s); err != nil {
+		return nil, err
+	}
+	// Encode adds a newline
+	return bytes.TrimSuffix(w.buf.Bytes(), []byte{'\n'}), nil
+}
+
+// write is an internal function to write data to the underlying writer and set the error.
+// If an error is already set, it does nothing.
+func (w *jsonLines) write(data []byte) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = w.bufw.Write(data)
+}
+
+// writeByte is an internal function to write a byte to the underlying writer and set the error.
+// If an error is already set, it does nothing.
+func (w *jsonLines) writeByte(c byte) {
+	if w.err != nil {
+		return
+	}
+	w.err = w.bufw.WriteByte(c)
+}
+
+// WriteRecord writes a record as a single line JSON object.
+// The keys are the header fields in the same order.
+// If the record is shorter than the header, the missing keys are omitted.
+// If the record is longer than the header, an error is set and nothing is written.
+func (w *jsonLines) WriteRecord(record []string) {
+	if w.err != nil {
+		return
+	}
+	if len(record) > len(w.header) {
+		w.err = errors.New("record has more fields than the header")
+		return
+	}
+	w.writeByte('{')
+	for i, field := range record {
+		if i > 0 {
+			w.writeByte(',')
+		}
+		w.write(w.header[i])
+		w.writeByte(':')
+		value, err := w.encode(field)
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		w.write(value)
+	}
+	w.writeByte('}')
+	w.writeByte('\n')
+}
+
+// Error returns any error encountered by the writer.
+func (w *jsonLines) Error() error {
+	return w.err
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *jsonLines) Flush() {
+	if w.err != nil {
+		return
+	}
+	w.err = w.bufw.Flush()
+}
@@ -0,0 +1,34 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONLines(t *testing.T) {
+	data := []struct {
+		header   []string
+		records  [][]string
+		expected string
+		err      bool
+	}{
+		{[]string{"a", "b"}, nil, "", false},
+		{[]string{"a", "b"}, [][]string{{"1", "2"}, {"3"}, {}}, "{\"a\":\"1\",\"b\":\"2\"}\n{\"a\":\"3\"}\n{}\n", false},
+		{[]string{"x\"y", "z"}, [][]string{{"a\nb", "<c>"}}, "{\"x\\\"y\":\"a\\nb\",\"z\":\"<c>\"}\n", false},
+		{[]string{"<&>"}, [][]string{{"a<&>b"}}, "{\"<&>\":\"a<&>b\"}\n", false},
+		{[]string{"a"}, [][]string{{"1"}, {"2", "3"}, {"4"}}, "{\"a\":\"1\"}\n", true},
+	}
+
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := NewJSONLines(&gotw, d.header).(*jsonLines)
+		for _, r := range d.records {
+			w.WriteRecord(r)
+		}
+		err := w.Error()
+		w.bufw.Flush()
+		if got := gotw.String(); got != d.expected || (err != nil) != d.err {
+			t.Errorf("for %q expected <%q> (error: %v), got <%q> (error: %v)", d.records, d.expected, d.err, got, err)
+		}
+	}
+}
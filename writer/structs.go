@@ -0,0 +1,143 @@
+package writer
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// structField is a field of a struct written by WriteStructs.
+type structField struct {
+	index     int    // the index of the field in the struct
+	name      string // the header name (the csv tag or the field name)
+	omitempty bool   // true if the zero value is written as an empty field
+}
+
+// structFields returns the exported fields of the struct type t that are not skipped by a `csv:"-"` tag.
+// The tag is `csv:"name"` or `csv:"name,option,..."` (an empty name is the field name) where omitempty is the only option used.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		omitempty := false
+		for _, opt := range strings.Split(opts, ",") {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		fields = append(fields, structField{index: i, name: name, omitempty: omitempty})
+	}
+	return fields
+}
+
+// WriteStructs writes a header row from the fields of the structs, and then a row for each element of v,
+// that is a slice (or an array) of structs or of pointers to structs.
+// The header is written by WriteHeaderRecord, so the following rows should have the same number of fields.
+// The columns are the exported fields with their names set by `csv:"name"` tags (the field name by default);
+// the fields with a `csv:"-"` tag are skipped and the zero value of a `csv:",omitempty"` field is an empty field.
+// The values are written with the Write...Field methods (the floats with the format set by WithFloatFormat), encoding.TextMarshaler and fmt.Stringer are used if implemented,
+// and the other values are formatted with fmt.Sprint. A nil pointer is an empty field.
+func (w *writer) WriteStructs(v interface{}) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if w.err == nil {
+			w.err = fmt.Errorf("WriteStructs expects a slice of structs, got %T", v)
+		}
+		return
+	}
+	t := rv.Type().Elem()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		if w.err == nil {
+			w.err = fmt.Errorf("WriteStructs expects a slice of structs, got %T", v)
+		}
+		return
+	}
+	fields := structFields(t)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = f.name
+	}
+	w.WriteHeaderRecord(header)
+	for i := 0; i < rv.Len() && w.err == nil; i++ {
+		elem := rv.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				w.err = errors.New("WriteStructs can not write a nil struct pointer")
+				return
+			}
+			elem = elem.Elem()
+		}
+		for _, f := range fields {
+			fv := elem.Field(f.index)
+			if f.omitempty && fv.IsZero() {
+				w.WriteByteField(nil)
+				continue
+			}
+			w.writeValue(fv)
+		}
+		w.NewRow()
+	}
+}
+
+// writeValue writes the value v as a single field.
+func (w *writer) writeValue(v reflect.Value) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			w.WriteByteField(nil)
+			return
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := x.MarshalText()
+		if err != nil {
+			if w.err == nil {
+				w.err = err
+			}
+			return
+		}
+		w.WriteByteField(text)
+		return
+	case fmt.Stringer:
+		w.WriteStringField(x.String())
+		return
+	case []byte:
+		w.WriteByteField(x)
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		w.WriteStringField(v.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		w.WriteIntField(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		w.numbuf = strconv.AppendUint(w.numbuf[:0], v.Uint(), 10)
+		w.WriteByteField(w.numbuf)
+	case reflect.Float32:
+		// format with 32 bits to avoid the artefacts of the conversion to float64
+		w.numbuf = strconv.AppendFloat(w.numbuf[:0], v.Float(), w.floatFmt, w.floatPrec, 32)
+		w.WriteByteField(w.numbuf)
+	case reflect.Float64:
+		w.WriteFloatField(v.Float(), 0, 0)
+	case reflect.Bool:
+		w.WriteBoolField(v.Bool())
+	default:
+		w.WriteStringField(fmt.Sprint(v.Interface()))
+	}
+}
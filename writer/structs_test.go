@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type level int
+
+func (l level) String() string {
+	return strings.Repeat("*", int(l))
+}
+
+func TestWriteStructs(t *testing.T) {
+	type item struct {
+		Name    string `csv:"name"`
+		Count   int    `csv:"count,omitempty"`
+		Price   float32
+		Ok      bool      `csv:"ok"`
+		Level   level     `csv:"level"`
+		When    time.Time `csv:"when,omitempty"`
+		Comment *string   `csv:"comment"`
+		Skipped string    `csv:"-"`
+		hidden  string
+	}
+	note := "a, b"
+	items := []item{
+		{Name: "x", Count: 2, Price: 0.1, Ok: true, Level: 3, Comment: &note, Skipped: "no", hidden: "no"},
+		{Name: "y", When: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	}
+	expected := "name,count,Price,ok,level,when,comment\n" +
+		"x,2,0.1,true,***,,\"a, b\"\n" +
+		"y,,0,false,,2024-01-02T03:04:05Z,\n"
+	for _, v := range []interface{}{items, []*item{&items[0], &items[1]}} {
+		gotw := strings.Builder{}
+		w := New(&gotw)
+		w.WriteStructs(v)
+		w.Flush()
+		if got := gotw.String(); got != expected || w.Error() != nil {
+			t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+		}
+	}
+	// omitempty among other options
+	type other struct {
+		A int `csv:"a,string,omitempty"`
+		B int `csv:",omitempty,other"`
+		C int `csv:"c,string"`
+	}
+	gotw := strings.Builder{}
+	w := New(&gotw)
+	w.WriteStructs([]other{{}})
+	w.Flush()
+	if got, expected := gotw.String(), "a,B,c\n,,0\n"; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+	// not a slice of structs
+	for _, v := range []interface{}{items[0], []int{1}, []*item{nil}} {
+		w := New(&strings.Builder{})
+		w.WriteStructs(v)
+		if w.Error() == nil {
+			t.Errorf("expected an error for %T", v)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package writer
+
+import (
+	"io"
+
+	"github.com/kpym/csv/scanner"
+)
+
+// Transform reads the rows from r with a scanner configured with ropts,
+// and writes them to w with a writer configured with wopts, after transforming them with fn.
+// If fn returns a nil row, the row is dropped, and if it returns an error, Transform stops and returns it.
+// The comments and the empty lines are written as they are read
+// (use WithRawComment for comments identical to the input), but a quoted empty row `""` is a data row passed to fn.
+// A row returned by fn with a single blank field (see scanner.IsBlankRow) is enquoted, so it is not read as an empty line.
+// The first scanner or writer error is returned.
+func Transform(r io.Reader, w io.Writer, fn func(row [][]byte) ([][]byte, error), ropts []scanner.Option, wopts []Option) error {
+	sc := scanner.New(r, ropts...)
+	cw := New(w, wopts...)
+	for {
+		row, ok := sc.NextRow()
+		if !ok {
+			break
+		}
+		switch {
+		case row.IsComment():
+			cw.WriteByteComment(row.Fields()[0])
+		case row.IsEmpty() && !row.IsQuoted():
+			cw.EmptyRow()
+		default:
+			fields, err := fn(row.Fields())
+			if err != nil {
+				cw.Flush()
+				return err
+			}
+			if fields == nil {
+				continue
+			}
+			if len(fields) == 1 && scanner.IsBlankRow(fields) {
+				cw.WriteScannedField(fields[0], true)
+			} else {
+				for _, f := range fields {
+					cw.WriteByteField(f)
+				}
+			}
+			cw.NewRow()
+		}
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return cw.Error()
+}
@@ -0,0 +1,53 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kpym/csv/scanner"
+)
+
+func TestTransform(t *testing.T) {
+	in := "#comment\na,b\n\nc,d\ne,\"f;g\"\n"
+	upper := func(row [][]byte) ([][]byte, error) {
+		if string(row[0]) == "c" {
+			return nil, nil
+		}
+		for i := range row {
+			row[i] = bytes.ToUpper(row[i])
+		}
+		return row, nil
+	}
+	gotw := strings.Builder{}
+	err := Transform(strings.NewReader(in), &gotw, upper, nil, []Option{WithSeparator(';'), WithRawComment()})
+	if got, expected := gotw.String(), "#comment\nA;B\n\nE;\"F;G\"\n"; got != expected || err != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, err)
+	}
+	// the error of fn is returned
+	errStop := errors.New("stop")
+	stop := func(row [][]byte) ([][]byte, error) {
+		if string(row[0]) == "c" {
+			return nil, errStop
+		}
+		return row, nil
+	}
+	gotw.Reset()
+	err = Transform(strings.NewReader(in), &gotw, stop, []scanner.Option{scanner.WithComment(nil)}, nil)
+	if got, expected := gotw.String(), "#comment\na,b\n\n"; got != expected || err != errStop {
+		t.Errorf("expected <%q> (error: %v), got <%q> (error: %v)", expected, errStop, got, err)
+	}
+	// a quoted empty row is a data row
+	gotw.Reset()
+	err = Transform(strings.NewReader("a,b\n\"\"\nc,d\n\" \"\n"), &gotw, upper, nil, nil)
+	if got, expected := gotw.String(), "A,B\n\"\"\n\" \"\n"; got != expected || err != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, err)
+	}
+	// the rows before a scanner error are written
+	gotw.Reset()
+	err = Transform(strings.NewReader("a,b\nc,d,e\n"), &gotw, upper, []scanner.Option{scanner.WithMaxColumns(2)}, nil)
+	if got, expected := gotw.String(), "A,B\n"; got != expected || !errors.Is(err, scanner.ErrTooManyColumns) {
+		t.Errorf("expected <%q> (error: %v), got <%q> (error: %v)", expected, scanner.ErrTooManyColumns, got, err)
+	}
+}
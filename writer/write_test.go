@@ -2,8 +2,14 @@ package writer
 
 import (
 	"bufio"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/kpym/csv/scanner"
 )
 
 func TestHasQuoteSep(t *testing.T) {
@@ -54,16 +60,721 @@ func TestWriteEscaped(t *testing.T) {
 		gotw := strings.Builder{}
 		// create a writer
 		w := &writer{
-			bufw:   bufio.NewWriter(&gotw),
+			out:    &gotw,
 			sep:    d.sep,
 			quote:  d.quote,
 			escape: d.quote,
 		}
 		w.writeEscaped([]byte(d.in))
-		w.bufw.Flush()
+		w.Flush()
 		got := gotw.String()
 		if got != d.expected {
 			t.Errorf("for <%s> expected <%s>, got <%s>", d.in, d.expected, got)
 		}
 	}
 }
+
+func TestEscapeField(t *testing.T) {
+	data := []struct {
+		in       string
+		opts     []Option
+		expected string
+	}{
+		{"", nil, ""},
+		{"test", nil, "test"},
+		{"a,b", nil, `"a,b"`},
+		{`a"b`, nil, `"a""b"`},
+		{"a\nb", nil, "\"a\nb\""},
+		{"a,b", []Option{WithSeparator(';')}, "a,b"},
+		{"test", []Option{WithEnquoteAny()}, `"test"`},
+		{`a'b`, []Option{WithQuote('\''), WithEscape('\\')}, `'a\'b'`},
+	}
+
+	for _, d := range data {
+		if got := EscapeField(d.in, d.opts...); got != d.expected {
+			t.Errorf("for <%s> expected <%s>, got <%s>", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestStrictComments(t *testing.T) {
+	data := []struct {
+		opts     []Option
+		expected string
+		err      bool
+	}{
+		{nil, "a\n# c\nb\n", false},
+		{[]Option{WithStrictComments()}, "a", true},
+	}
+
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, d.opts...).(*writer)
+		w.WriteStringField("a")
+		w.WriteStringComment("c")
+		w.WriteStringField("b")
+		w.NewRow()
+		err := w.Error()
+		w.Flush()
+		if got := gotw.String(); got != d.expected || (err != nil) != d.err {
+			t.Errorf("expected <%q> (error: %v), got <%q> (error: %v)", d.expected, d.err, got, err)
+		}
+	}
+}
+
+func TestWriteNumericFields(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw)
+	w.WriteIntField(-42)
+	w.WriteFloatField(3.14159, 'f', 2)
+	w.WriteFloatField(1e21, 'g', -1)
+	w.WriteBoolField(true)
+	w.NewRow()
+	w.Flush()
+	if got, expected := gotw.String(), "-42,3.14,1e+21,true\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	// numeric fields are enquoted if needed
+	gotw.Reset()
+	w = New(&gotw, WithSeparator('.'))
+	w.WriteFloatField(1.5, 'f', 1)
+	w.Flush()
+	if got, expected := gotw.String(), `"1.5"`; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestWriteNumericFieldsAllocs(t *testing.T) {
+	w := New(io.Discard)
+	allocs := testing.AllocsPerRun(100, func() {
+		w.WriteIntField(1234567)
+		w.WriteFloatField(1234.567, 'g', -1)
+		w.WriteBoolField(false)
+		w.NewRow()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestIsAmbiguousNumber(t *testing.T) {
+	data := []struct {
+		in       string
+		expected bool
+	}{
+		{"", false},
+		{"0", false},
+		{"7", false},
+		{"123", false},
+		{"007", true},
+		{"0123456789", true},
+		{"123456789012345", false},
+		{"1234567890123456", true},
+		{"0.5", false},
+		{"-01", false},
+		{"0a", false},
+	}
+	for _, d := range data {
+		if got := isAmbiguousNumber([]byte(d.in)); got != d.expected {
+			t.Errorf("for <%s> expected <%v>, got <%v>", d.in, d.expected, got)
+		}
+	}
+}
+
+func TestQuoteAmbiguousNumbers(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithQuoteAmbiguousNumbers())
+	for _, f := range []string{"007", "7", "1234567890123456", "a,b"} {
+		w.WriteStringField(f)
+	}
+	w.Flush()
+	if got, expected := gotw.String(), `"007",7,"1234567890123456","a,b"`; got != expected {
+		t.Errorf("expected <%s>, got <%s>", expected, got)
+	}
+}
+
+func TestQuoteEdgeSpaces(t *testing.T) {
+	fields := []string{" leading", "trailing ", "\tboth\t", "in side", "", " "}
+	gotw := strings.Builder{}
+	w := New(&gotw, WithQuoteEdgeSpaces())
+	for _, f := range fields {
+		w.WriteStringField(f)
+	}
+	w.Flush()
+	if got, expected := gotw.String(), "\" leading\",\"trailing \",\"\tboth\t\",in side,,\" \""; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	// round trip with the fuzzy scanner
+	sc := scanner.New(strings.NewReader(gotw.String()), scanner.WithQuote('"', scanner.QuoteFuzzy))
+	for i := 0; sc.Scan(); i++ {
+		if i >= len(fields) || string(sc.Bytes()) != fields[i] {
+			t.Errorf("field %d read as <%q>", i, sc.Bytes())
+		}
+	}
+}
+
+func TestPadRows(t *testing.T) {
+	data := []struct {
+		rows     [][]string
+		expected string
+		err      bool
+	}{
+		{[][]string{{"a", "b", "c"}, {"d"}, {}, {"e", "f"}}, "a,b,c\nd,,\n\ne,f,\n", false},
+		{[][]string{{"a"}, {"b", "c", "d", "e"}}, "a,,\nb,c,d", true},
+	}
+
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, WithPadRows(3)).(*writer)
+		for _, row := range d.rows {
+			if len(row) == 0 {
+				w.EmptyRow()
+				continue
+			}
+			for _, f := range row {
+				w.WriteStringField(f)
+			}
+			w.NewRow()
+		}
+		err := w.Error()
+		w.Flush()
+		if got := gotw.String(); got != d.expected || (err != nil) != d.err {
+			t.Errorf("for %q expected <%q> (error: %v), got <%q> (error: %v)", d.rows, d.expected, d.err, got, err)
+		}
+	}
+}
+
+func TestRawComment(t *testing.T) {
+	data := []struct {
+		opts     []Option
+		comment  string
+		expected string
+	}{
+		{nil, " text ", "#  text\n"},
+		{[]Option{WithRawComment()}, " text ", "# text \n"},
+		{[]Option{WithRawComment()}, "text\r\n second\r\n", "#text\n# second\n"},
+		{[]Option{WithComment([]byte("//")), WithRawComment()}, "text", "//text\n"},
+		{[]Option{WithComment([]byte("# ")), WithRawComment()}, "text", "# text\n"},
+		{[]Option{WithRawComment(), WithComment([]byte("# "))}, " text", "#  text\n"},
+	}
+
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, d.opts...)
+		w.WriteStringComment(d.comment)
+		w.Flush()
+		if got := gotw.String(); got != d.expected {
+			t.Errorf("for <%q> expected <%q>, got <%q>", d.comment, d.expected, got)
+		}
+	}
+}
+
+func TestBlankCommentLines(t *testing.T) {
+	data := []struct {
+		opts     []Option
+		expected string
+	}{
+		{nil, "# a\n# \n#  \t\n# b\n"},
+		{[]Option{WithBlankCommentLines(false)}, "# a\n# \n#  \t\n# b\n"},
+		{[]Option{WithBlankCommentLines(true)}, "# a\n\n\n# b\n"},
+		{[]Option{WithBlankCommentLines(true), WithRawComment()}, "#a\n\n\n#b\n"},
+	}
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, d.opts...)
+		w.WriteStringComment("a\n\n \t\nb\n\n")
+		w.Flush()
+		if got := gotw.String(); got != d.expected {
+			t.Errorf("expected <%q>, got <%q>", d.expected, got)
+		}
+	}
+}
+
+func TestEscapeNewlines(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithEscapeNewlines())
+	for _, f := range []string{"a\nb", "c\r\nd", "e,\nf", `g\n`} {
+		w.WriteStringField(f)
+	}
+	w.Flush()
+	if got, expected := gotw.String(), `a\nb,c\r\nd,"e,\nf",g\n`; got != expected {
+		t.Errorf("expected <%s>, got <%s>", expected, got)
+	}
+}
+
+func TestAbortRow(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithPadRows(2))
+	w.WriteStringField("a")
+	w.WriteStringField("b")
+	w.NewRow()
+	w.WriteStringField("c")
+	w.AbortRow()
+	w.WriteStringField("d")
+	w.WriteStringField("e")
+	w.NewRow()
+	w.WriteStringField("f")
+	w.AbortRow()
+	w.Flush()
+	w.AbortRow()
+	if got, expected := gotw.String(), "a,b\nd,e\n"; got != expected || w.Error() != nil || !w.AtRowStart() {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+
+	// the start of the row written by Flush can not be dropped
+	gotw.Reset()
+	w = New(&gotw)
+	w.WriteStringField("a")
+	w.WriteStringField("b")
+	w.Flush()
+	w.WriteStringField("c")
+	w.AbortRow()
+	w.WriteStringField("d")
+	w.NewRow()
+	w.Flush()
+	if got, expected := gotw.String(), "a,b,c"; got != expected || w.Error() == nil {
+		t.Errorf("expected <%q> and an error, got <%q> (error: %v)", expected, got, w.Error())
+	}
+}
+
+func TestEnquoteNever(t *testing.T) {
+	data := []struct {
+		opts     []Option
+		expected string
+		err      bool
+	}{
+		{[]Option{WithEnquoteNever()}, "a,b,c\"d,e", false},
+		{[]Option{WithEnquoteNeverChecked()}, "a", true},
+	}
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, d.opts...).(*writer)
+		for _, f := range []string{"a", "b,c\"d", "e"} {
+			w.WriteStringField(f)
+		}
+		err := w.Error()
+		w.Flush()
+		if got := gotw.String(); got != d.expected || (err != nil) != d.err {
+			t.Errorf("expected <%q> (error: %v), got <%q> (error: %v)", d.expected, d.err, got, err)
+		}
+	}
+}
+
+func TestEnquoteFunc(t *testing.T) {
+	// enquote the first column, and the fields containing a space
+	fn := func(col int, field []byte) bool {
+		return col == 0 || strings.Contains(string(field), " ")
+	}
+	gotw := strings.Builder{}
+	w := New(&gotw, WithEnquoteFunc(fn), WithQuoteEdgeSpaces())
+	w.WriteRecord([]string{"a", "b c", "d"})
+	w.WriteRecord([]string{"e", "f", " g"})
+	w.Flush()
+	if got, expected := gotw.String(), "\"a\",\"b c\",d\n\"e\",f,\" g\"\n"; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	data := []struct {
+		opts     []Option
+		expected string
+	}{
+		{[]Option{WithEnquoteAny(), WithTrailingSeparator(), WithCanonical()}, "a,b,\"c,d\"\n\"e\nf\",g\n"},
+		{[]Option{WithEscapeOnly('\\'), WithCanonical()}, "a,b,\"c,d\"\n\"e\nf\",g\n"},
+		{[]Option{WithCanonical(), WithQuoteEdgeSpaces()}, "a,\"b  \",\"c,d\"\n\"e\nf\",\"g\t\"\n"},
+	}
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, d.opts...)
+		w.WriteRecord([]string{"a", "b  ", "c,d"})
+		w.WriteRecord([]string{"e\r\nf", "g\t"})
+		w.Finalize()
+		if got := gotw.String(); got != d.expected || w.Error() != nil {
+			t.Errorf("expected <%q>, got <%q> (error: %v)", d.expected, got, w.Error())
+		}
+	}
+	// the same logical data gives the same output
+	gotw := strings.Builder{}
+	w := New(&gotw, WithCanonical())
+	w.WriteRecord([]string{"x\ry", "z"})
+	w.WriteRecord([]string{"x\ny", "z "})
+	w.Finalize()
+	if got, expected := gotw.String(), "\"x\ny\",z\n\"x\ny\",z\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestWriteScannedField(t *testing.T) {
+	in := "\"a\",b,\"c\"\"d\",\"\"\n1,\"2\",\"3,4\",5\n"
+	sc := scanner.New(strings.NewReader(in), scanner.WithQuote('"', scanner.QuoteStrict))
+	gotw := strings.Builder{}
+	w := New(&gotw)
+	for sc.Scan() {
+		w.WriteScannedField(sc.Bytes(), sc.IsQuoted())
+		if sc.AtRowEnd() {
+			w.NewRow()
+		}
+	}
+	w.Flush()
+	if got := gotw.String(); got != in || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", in, got, w.Error())
+	}
+	// the escaped quotes are written as the writer escapes them
+	gotw.Reset()
+	w = New(&gotw)
+	w.WriteScannedField([]byte(`a"b`), true)
+	w.WriteScannedField([]byte(`c`), false)
+	w.Flush()
+	if got, expected := gotw.String(), `"a""b",c`; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestRowHook(t *testing.T) {
+	gotw := strings.Builder{}
+	offsets := []int64{}
+	w := New(&gotw, WithRowHook(func(rowIndex int, offset int64) {
+		if rowIndex != len(offsets) {
+			t.Errorf("expected row index %d, got %d", len(offsets), rowIndex)
+		}
+		offsets = append(offsets, offset)
+	}))
+	w.WriteStringField("a")
+	w.WriteStringField("b,c")
+	w.NewRow()
+	w.EmptyRow()
+	w.WriteStringComment("comment")
+	w.WriteStringField("d")
+	w.NewRow()
+	w.NewRow()
+	w.Flush()
+	if got, expected := fmt.Sprint(offsets), "[8 9 21]"; got != expected {
+		t.Errorf("expected offsets %s, got %s (output <%q>)", expected, got, gotw.String())
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	n := len(DefaultOptions)
+	if got := EscapeField("a;b", Defaults(WithSeparator(';'))...); got != `"a;b"` || len(DefaultOptions) != n {
+		t.Errorf("expected <\"a;b\"> and %d default options, got <%s> and %d", n, got, len(DefaultOptions))
+	}
+}
+
+func TestDedupConsecutiveRows(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithDedupConsecutiveRows())
+	for _, row := range [][]string{{"a", "b"}, {"a", "b"}, {"a,b"}, {"a", "b"}, {"a", "b"}, {}, {"a", "b"}, {"c"}, {"c"}} {
+		if len(row) == 0 {
+			w.EmptyRow()
+			continue
+		}
+		for _, f := range row {
+			w.WriteStringField(f)
+		}
+		w.NewRow()
+	}
+	w.WriteStringComment("x")
+	w.WriteStringField("c")
+	w.NewRow()
+	w.Flush()
+	if got, expected := gotw.String(), "a,b\n\"a,b\"\na,b\n\na,b\nc\n# x\nc\n"; got != expected || w.SuppressedRows() != 3 {
+		t.Errorf("expected <%q> and 3 suppressed rows, got <%q> and %d", expected, got, w.SuppressedRows())
+	}
+}
+
+func TestEscapedSeparators(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithEscape('\\'), WithEscapedSeparators())
+	for _, f := range []string{"a,b", `c\`, "d\"e", "f\ng,"} {
+		w.WriteStringField(f)
+	}
+	w.Flush()
+	if got, expected := gotw.String(), "a\\,b,c\\\\,\"d\\\"e\",\"f\ng,\""; got != expected || w.Error() != nil {
+		t.Errorf("expected <%s>, got <%s> (error: %v)", expected, got, w.Error())
+	}
+	if w := New(io.Discard, WithEscapedSeparators()); w.Error() == nil {
+		t.Errorf("expected an error when the escape is the quote")
+	}
+}
+
+func TestWriteHeaderRecord(t *testing.T) {
+	data := []struct {
+		rows     [][]string
+		expected string
+		err      bool
+	}{
+		{[][]string{{"a", "b"}, {"c", "d"}}, "x,y\na,b\nc,d\n", false},
+		{[][]string{{"a", "b"}, {"c", "d", "e"}}, "x,y\na,b\nc,d", true},
+		{[][]string{{"a"}, {"c", "d"}}, "x,y\na", true},
+	}
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw).(*writer)
+		w.WriteHeaderRecord([]string{"x", "y"})
+		w.WriteStringComment("c")
+		w.EmptyRow()
+		for _, row := range d.rows {
+			w.WriteRecord(row)
+		}
+		err := w.Error()
+		w.Flush()
+		expected := strings.Replace(d.expected, "x,y\n", "x,y\n# c\n\n", 1)
+		if got := gotw.String(); got != expected || (err != nil) != d.err {
+			t.Errorf("for %q expected <%q> (error: %v), got <%q> (error: %v)", d.rows, expected, d.err, got, err)
+		}
+	}
+}
+
+func TestCommentWrap(t *testing.T) {
+	data := []struct {
+		width    int
+		comment  string
+		expected string
+	}{
+		{10, "short", "# short\n"},
+		{10, "one two three four", "# one two\n# three\n# four\n"},
+		{10, "été à la plage", "# été à la\n# plage\n"},
+		{6, "abcdefghij k", "# abcd\n# efgh\n# ij k\n"},
+		{10, "a\nb c d e f g", "# a\n# b c d e\n# f g\n"},
+	}
+	for _, d := range data {
+		gotw := strings.Builder{}
+		w := New(&gotw, WithCommentWrap(d.width))
+		w.WriteStringComment(d.comment)
+		w.Flush()
+		if got := gotw.String(); got != d.expected {
+			t.Errorf("for <%q> expected <%q>, got <%q>", d.comment, d.expected, got)
+		}
+	}
+}
+
+func TestWritePreamble(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw)
+	w.WritePreamble([]byte("Report\r\n  generated\n\n"))
+	w.WriteStringField("a")
+	w.WritePreamble([]byte("raw,\"line\"\n"))
+	if !w.AtRowStart() {
+		t.Errorf("expected to be at row start after the preamble")
+	}
+	w.WriteStringField("b")
+	w.Flush()
+	if got, expected := gotw.String(), "Report\r\n  generated\n\na\nraw,\"line\"\nb"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestFloatFormat(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithFloatFormat('f', 2))
+	w.WriteFloatField(1.5, 0, 0)
+	w.WriteFloatField(1.5, 'e', 1)
+	w.WriteStringField("1.5")
+	w.NewRow()
+	w.WriteStructs([]struct {
+		A float64
+		B float32
+	}{{1.0 / 3, 2}})
+	w.Flush()
+	if got, expected := gotw.String(), "1.50,1.5e+00,1.5\nA,B\n0.33,2.00\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestTrailingSeparator(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithTrailingSeparator())
+	w.WriteRecord([]string{"a", "b", "c"})
+	w.WriteStringComment("comment")
+	w.EmptyRow()
+	w.WriteRecord([]string{"1", "", ""})
+	w.WriteRecord([]string{""})
+	w.Flush()
+	expected := "a,b,c,\n# comment\n\n1,,,\n,\n"
+	if got := gotw.String(); got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	// round trip with the scanner
+	sc := scanner.New(strings.NewReader(gotw.String()), scanner.WithIgnoreTrailingSeparator())
+	got := []string{}
+	for {
+		row, ok := sc.NextRow()
+		if !ok {
+			break
+		}
+		got = append(got, fmt.Sprintf("%q", row.Fields()))
+	}
+	if expected := `["a" "b" "c"]|[" comment"]|[""]|["1" "" ""]|[""]`; strings.Join(got, "|") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, "|"))
+	}
+}
+
+func TestCommentPrefixRoundTrip(t *testing.T) {
+	// the comment text starts with the prefix, that is not removed twice by the scanner
+	for _, prefix := range []string{"#", "# ", "//"} {
+		gotw := strings.Builder{}
+		w := New(&gotw, WithComment([]byte(prefix)))
+		w.WriteStringComment("# literal\n" + prefix + "x")
+		w.WriteRecord([]string{"a"})
+		w.Flush()
+		sc := scanner.New(strings.NewReader(gotw.String()), scanner.WithComment([]byte(prefix)))
+		got := []string{}
+		for sc.Scan() {
+			got = append(got, fmt.Sprintf("%q", sc.Bytes()))
+		}
+		if expected := fmt.Sprintf("%q|%q|\"a\"", "# literal", prefix+"x"); strings.Join(got, "|") != expected {
+			t.Errorf("for prefix <%q> expected %s, got %s", prefix, expected, strings.Join(got, "|"))
+		}
+	}
+}
+
+func TestBufferedOutput(t *testing.T) {
+	// a *bufio.Writer is used directly and is not flushed
+	gotw := strings.Builder{}
+	bw := bufio.NewWriter(&gotw)
+	w := New(bw)
+	w.WriteRecord([]string{"a", "b"})
+	w.WriteStringField("c")
+	w.Flush()
+	if got, buffered := gotw.String(), bw.Buffered(); got != "" || buffered != len("a,b\nc") {
+		t.Errorf("expected nothing written and 5 bytes buffered, got <%q> and %d bytes", got, buffered)
+	}
+	bw.Flush()
+	if got, expected := gotw.String(), "a,b\nc"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	// without buffer, each terminated row is written
+	gotw.Reset()
+	w = New(&gotw, WithNoBuffer())
+	w.WriteRecord([]string{"a", "b"})
+	w.WriteStringField("c")
+	if got, expected := gotw.String(), "a,b\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+	w.Flush()
+	if got, expected := gotw.String(), "a,b\nc"; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+	// the buffered rows are written when the buffer is full
+	full := strings.Builder{}
+	w = New(&full)
+	for i := 0; i < bufferSize/4; i++ {
+		w.WriteRecord([]string{"abc"})
+	}
+	if full.Len() != bufferSize {
+		t.Errorf("expected %d bytes written before Flush, got %d", bufferSize, full.Len())
+	}
+	// Reset does not reset the *bufio.Writer of the caller
+	other := strings.Builder{}
+	w = New(bw)
+	w.WriteRecord([]string{"d"})
+	w.Reset(&other)
+	w.WriteRecord([]string{"e"})
+	w.Flush()
+	bw.Flush()
+	if got, expected := gotw.String()+"|"+other.String(), "a,b\ncd\n|e\n"; got != expected {
+		t.Errorf("expected <%q>, got <%q>", expected, got)
+	}
+}
+
+func TestRawCommentsRoundTrip(t *testing.T) {
+	in := "#  a\nb,c\n// d \n"
+	sc := scanner.New(strings.NewReader(in), scanner.WithComments([]byte("#"), []byte("//")), scanner.WithRawComments())
+	gotw := strings.Builder{}
+	w := New(&gotw, WithComment(nil), WithRawComment())
+	for sc.Scan() {
+		if sc.IsComment() {
+			w.WriteByteComment(sc.Bytes())
+			continue
+		}
+		w.WriteByteField(sc.Bytes())
+		if sc.AtRowEnd() {
+			w.NewRow()
+		}
+	}
+	w.Flush()
+	if got := gotw.String(); got != in || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", in, got, w.Error())
+	}
+}
+
+func TestReset(t *testing.T) {
+	first, second := strings.Builder{}, strings.Builder{}
+	w := New(&first, WithSeparator(';'))
+	w.WriteHeaderRecord([]string{"a", "b"})
+	w.WriteStringField("x")
+	w.WriteStringField("y")
+	w.WriteStringField("z") // error: too many fields
+	w.Reset(&second)
+	if w.Error() != nil || !w.AtRowStart() {
+		t.Errorf("expected no error at the row start, got %v", w.Error())
+	}
+	// the header of the first output is not required anymore
+	w.WriteRecord([]string{"1", "2", "3"})
+	w.WriteRecord([]string{"4"})
+	w.Flush()
+	if first.String() != "" {
+		t.Errorf("expected nothing written to the first writer, got <%q>", first.String())
+	}
+	if got, expected := second.String(), "1;2;3\n4\n"; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+}
+
+func TestChecksumComment(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithChecksumComment(crc32.NewIEEE()))
+	w.WriteRecord([]string{"a", "b"})
+	w.WriteStringComment("note")
+	w.EmptyRow()
+	w.WriteStringField("dropped")
+	w.AbortRow()
+	w.WriteRecord([]string{"1", "2,3"})
+	w.WriteRecord([]string{""})
+	w.Finalize()
+	sum := crc32.NewIEEE()
+	sum.Write([]byte("a\x1fb\x1f\x1e1\x1f2,3\x1f\x1e"))
+	expected := "a,b\n# note\n\n1,\"2,3\"\n\n# checksum: " + hex.EncodeToString(sum.Sum(nil)) + "\n"
+	if got := gotw.String(); got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+	if ok, err := scanner.VerifyChecksum(strings.NewReader(gotw.String()), crc32.NewIEEE()); !ok || err != nil {
+		t.Errorf("expected a valid checksum (error: %v)", err)
+	}
+
+	// the blank rows (quoted or not) are skipped by both sides
+	// and the checksum comment is not wrapped
+	for _, opts := range [][]Option{nil, {WithEnquoteAny()}, {WithCommentWrap(12)}} {
+		out := strings.Builder{}
+		w := New(&out, append(opts, WithChecksumComment(crc32.NewIEEE()))...)
+		w.WriteRecord([]string{"a", "b"})
+		w.WriteRecord([]string{" "})
+		w.WriteRecord([]string{""})
+		w.WriteRecord([]string{" \t "})
+		w.WriteRecord([]string{"c"})
+		w.Finalize()
+		if ok, err := scanner.VerifyChecksum(strings.NewReader(out.String()), crc32.NewIEEE()); !ok || err != nil {
+			t.Errorf("expected a valid checksum for <%q> (error: %v)", out.String(), err)
+		}
+	}
+}
+
+func TestEscapeOnly(t *testing.T) {
+	gotw := strings.Builder{}
+	w := New(&gotw, WithEscapeOnly('\\'))
+	w.WriteRecord([]string{"a,b", "a\nb", `c\d`, `"e"`, "f\r\n"})
+	w.Flush()
+	if got, expected := gotw.String(), `a\,b,a\nb,c\\d,"e",f\r\n`+"\n"; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+	// the options that enquote some fields are ignored
+	gotw.Reset()
+	w = New(&gotw, WithEscapeOnly('\\'), WithQuoteAmbiguousNumbers(), WithQuoteEdgeSpaces())
+	w.WriteRecord([]string{"007", " a,b "})
+	w.WriteScannedField([]byte("c,d"), true)
+	w.Flush()
+	if got, expected := gotw.String(), `007, a\,b `+"\n"+`c\,d`; got != expected || w.Error() != nil {
+		t.Errorf("expected <%q>, got <%q> (error: %v)", expected, got, w.Error())
+	}
+}
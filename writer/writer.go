@@ -3,8 +3,16 @@ package writer
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/kpym/csv/scanner"
 )
 
 // Writer interface
@@ -15,6 +23,35 @@ type Writer interface {
 	// WriteStringField writes a single CSV record along with any necessary quoting and escaping.
 	WriteStringField(field string)
 
+	// WriteScannedField writes a field read by a scanner, enquoted if it was quoted in the input (see Scanner.IsQuoted).
+	WriteScannedField(field []byte, wasQuoted bool)
+
+	// WriteIntField writes a single integer CSV field.
+	WriteIntField(field int64)
+
+	// WriteFloatField writes a single float CSV field formatted as strconv.FormatFloat(field, fmt, prec, 64).
+	// If fmt is 0, the format set by WithFloatFormat is used.
+	WriteFloatField(field float64, fmt byte, prec int)
+
+	// WriteBoolField writes a single boolean CSV field ("true" or "false").
+	WriteBoolField(field bool)
+
+	// WriteRecord writes the fields of a record and terminates the row.
+	WriteRecord(record []string)
+
+	// WriteFrom writes the records received from ch until it is closed, and returns the first error.
+	WriteFrom(ch <-chan []string) error
+
+	// WriteFromContext is WriteFrom, but it stops when ctx is done.
+	WriteFromContext(ctx context.Context, ch <-chan []string) error
+
+	// WriteHeaderRecord writes the header and then requires all rows to have the same number of fields.
+	WriteHeaderRecord(header []string)
+
+	// WriteStructs writes a header from the csv tags of the structs and then a row for each element of v,
+	// a slice of structs or of pointers to structs.
+	WriteStructs(v interface{})
+
 	// NewRow writes the end-of-line marker only if not at the beginning of a line.
 	NewRow()
 
@@ -27,28 +64,81 @@ type Writer interface {
 	// EmptyRow writes an empty line followed by the end-of-line marker.
 	EmptyRow()
 
+	// WritePreamble writes raw bytes as they are (like a preamble found by sniffer.LenPreamble).
+	WritePreamble(raw []byte)
+
 	// Flush writes any buffered data to the underlying io.Writer.
 	Flush()
 
+	// Finalize terminates the current row, writes the checksum comment (see WithChecksumComment) and flushes.
+	Finalize()
+
 	// Error reports any error that has occurred during a previous Write or Flush.
 	Error() error
 
 	// AtRowStart returns true if NewRow() or WriteByteComment() was called before
 	AtRowStart() bool
-}
 
-type writer struct {
-	bufw    *bufio.Writer // underlying buffered writer
-	err     error         // error encountered by the writer
-	sep     byte          // separator character (default ',')
-	quote   byte          // quote character (default '"')
-	escape  byte          // escape character (default '"')
-	comment []byte        // comment characters (default "#")
+	// AbortRow drops the fields written in the current row (since the last NewRow).
+	AbortRow()
 
-	qsnl      string            // string used by bytes.indexAny to find quote, sep, \n or \r
-	toEnquote func([]byte) bool // function to enquote a field
+	// SuppressedRows returns the number of rows not written because of WithDedupConsecutiveRows.
+	SuppressedRows() int
 
-	atRowStart bool // true if at the beginning of a line
+	// Reset discards the unflushed data and the error, and makes the writer write to dst with the same options.
+	Reset(dst io.Writer)
+}
+
+type writer struct {
+	out      io.Writer // the io.Writer given to New
+	buf      []byte    // the data not yet written to out, the current row starting at rowStart
+	rowStart int       // position of the current row in buf (-1 if the start of the row was written to out by Flush)
+	direct   bool      // true if each terminated row is written to out (a *bufio.Writer, or with WithNoBuffer)
+	noBuffer bool      // true if the io.Writer given to New is not buffered (see WithNoBuffer)
+	err      error     // error encountered by the writer
+	sep      byte      // separator character (default ',')
+	quote    byte      // quote character (default '"')
+	escape   byte      // escape character (default '"')
+	comment  []byte    // comment characters (default "#")
+
+	qsnl      string                           // string used by bytes.indexAny to find quote, sep, \n or \r
+	toEnquote func(col int, field []byte) bool // function to enquote a field (col starts at 0)
+
+	numbuf []byte // buffer reused to format numeric fields
+	nlbuf  []byte // buffer reused to escape the newlines of the fields
+
+	floatFmt  byte // format of the floats written by WriteFloatField with fmt 0 and by WriteStructs (default 'g')
+	floatPrec int  // precision of the floats written by WriteFloatField with fmt 0 and by WriteStructs (default -1)
+
+	cols    int // number of fields written in the current row
+	padRows int // number of fields of the padded rows (0 if no padding)
+	columns int // number of fields required in each row, set by WriteHeaderRecord (0 if not required)
+	rows    int // number of rows (including empty ones) terminated so far
+
+	dedup      bool   // true if a row identical to the previous one is not written
+	lastRow    []byte // the previous row (used only if dedup is true)
+	suppressed int    // number of rows not written because of dedup
+
+	checksum hash.Hash // hash of the data rows written as a final comment by Finalize (nil if none)
+	rowSum   []byte    // the normalized fields of the current row, added to checksum when the row is written
+
+	written int64                            // number of bytes written to out
+	rowHook func(rowIndex int, offset int64) // called after each row terminator (nil if none)
+
+	atRowStart     bool // true if at the beginning of a line
+	strictComments bool // true if comments are not allowed in the middle of a row
+	quoteNumbers   bool // true if ambiguous numbers should be enquoted
+	quoteEdges     bool // true if the fields with leading or trailing spaces or tabs should be enquoted
+	rawComment     bool // true if the comments are written without added or removed spaces
+	defaultComment bool // true if the comment prefix is the default one (written without its trailing space by WithRawComment)
+	blankComment   bool // true if the blank lines inside the comments are written as empty lines (without prefix)
+	commentWrap    int  // maximal width of the comment lines, including the prefix (0 if no wrapping)
+	escapeNewlines bool // true if \n and \r in fields are written as the two characters \n and \r
+	escapeSeps     bool // true if the separators in unquoted fields are escaped instead of enquoting the field
+	trailingSep    bool // true if the non empty rows end with a separator
+	escapeOnly     bool // true if the fields are never enquoted but escaped (see WithEscapeOnly)
+	forceQuote     bool // true if the next field is enquoted whatever the enquote mode is (see WriteScannedField)
+	canonical      bool // true if the line endings of the fields are normalized and the unquoted fields trimmed (see WithCanonical)
 }
 
 // Option is a function that sets an option on the writer.
@@ -82,28 +172,303 @@ func WithEscape(escape byte) Option {
 
 // WithComment sets the comment characters.
 // Comment characters should not be the same as the separator, the quote, newline or carriage return.
+// The prefix is added to each comment line, even if the text already starts with it,
+// so a scanner with the same prefix reads back the original text.
 func WithComment(comment []byte) Option {
 	return func(w *writer) {
 		w.comment = comment
+		w.defaultComment = false
+	}
+}
+
+// withDefaultComment sets the default comment prefix "# ".
+func withDefaultComment() Option {
+	return func(w *writer) {
+		w.comment = []byte("# ")
+		w.defaultComment = true
+	}
+}
+
+// WithStrictComments makes writing a comment in the middle of a row an error.
+// By default the current row is terminated before the comment.
+func WithStrictComments() Option {
+	return func(w *writer) {
+		w.strictComments = true
+	}
+}
+
+// WithRawComment writes the comments verbatim, to round-trip the comments read by the scanner.
+// The default comment prefix "# " is written without its trailing space (a prefix set by WithComment is written as is)
+// and the trailing spaces of the comment lines are kept.
+// So a comment " text" read by a scanner with prefix "#" is written back as "# text",
+// and a comment "text" as "#text".
+func WithRawComment() Option {
+	return func(w *writer) {
+		w.rawComment = true
+	}
+}
+
+// WithBlankCommentLines sets how the blank lines (empty or with only spaces and tabs) inside a multi-line comment are written.
+// If empty is true, they are written as empty lines without the comment prefix (read as empty lines by the scanner),
+// otherwise (the default) they are written as comment lines with only the prefix.
+// The trailing blank lines of the comments are always removed.
+func WithBlankCommentLines(empty bool) Option {
+	return func(w *writer) {
+		w.blankComment = empty
+	}
+}
+
+// WithEscapeNewlines writes the '\n' and '\r' of the fields as the two characters `\n` and `\r`,
+// so that every record is on a single line (useful for logs).
+// This is a one-way transformation: backslashes are not escaped,
+// so a reader can not distinguish an escaped newline from a literal `\n`.
+func WithEscapeNewlines() Option {
+	return func(w *writer) {
+		w.escapeNewlines = true
+	}
+}
+
+// WithRowHook sets a function called after the terminator of each row (including the empty ones) is written.
+// The rowIndex starts at 0 and does not count the comment lines.
+// The offset is the number of bytes written so far, ie the offset of the next row.
+// It could be used to build an index of the rows or a running checksum.
+func WithRowHook(fn func(rowIndex int, offset int64)) Option {
+	return func(w *writer) {
+		w.rowHook = fn
+	}
+}
+
+// WithCommentWrap wraps the comment lines longer than width (in runes, including the comment prefix)
+// at the spaces between words. The words longer than the available width are broken.
+// If width is 0 (default), the comments are not wrapped.
+func WithCommentWrap(width int) Option {
+	return func(w *writer) {
+		w.commentWrap = width
+	}
+}
+
+// wrapLine splits line in lines of at most width runes, at the spaces if possible.
+// The spaces at the breaks are removed.
+func wrapLine(line []byte, width int) [][]byte {
+	width = max(width, 1)
+	var lines [][]byte
+	for utf8.RuneCount(line) > width {
+		// the byte index after width runes
+		cut := 0
+		for n := 0; n < width; n++ {
+			_, size := utf8.DecodeRune(line[cut:])
+			cut += size
+		}
+		i := cut
+		if line[cut] != ' ' {
+			i = bytes.LastIndexByte(line[:cut], ' ')
+		}
+		if i <= 0 {
+			// no space to break, break the word
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+			continue
+		}
+		lines = append(lines, line[:i])
+		line = bytes.TrimLeft(line[i:], " ")
+	}
+	return append(lines, line)
+}
+
+// WithDedupConsecutiveRows skips the rows that are identical (field by field) to the previous row.
+// The rows separated by a comment or an empty row are not compared.
+// The number of skipped rows is returned by SuppressedRows().
+func WithDedupConsecutiveRows() Option {
+	return func(w *writer) {
+		w.dedup = true
+	}
+}
+
+// WithEscapedSeparators escapes the separators (and the escape characters) in the unquoted fields,
+// instead of enquoting the fields containing separators (like a\,b for "a,b" if the escape character is '\\').
+// The escape character (see WithEscape) should be different from the quote character.
+func WithEscapedSeparators() Option {
+	return func(w *writer) {
+		w.escapeSeps = true
+	}
+}
+
+// WithEscapeOnly never enquote the fields, but escapes with escape the separators, the escape characters,
+// the newlines and the carriage returns (written as <escape>n and <escape>r),
+// like a\,b for "a,b" and a\nb for "a\nb" if escape is '\\'. The quote character has no special meaning.
+// The fields are not enquoted even with WithQuoteAmbiguousNumbers, WithQuoteEdgeSpaces or by WriteScannedField.
+func WithEscapeOnly(escape byte) Option {
+	return func(w *writer) {
+		w.escape = escape
+		w.escapeOnly = true
+		w.toEnquote = func(int, []byte) bool { return false }
+	}
+}
+
+// WithNoBuffer makes the writer write each terminated row directly to the io.Writer given to New,
+// for the callers that manage the buffering themselves. The current row is still kept until it is terminated (or flushed).
+// Without this option, the io.Writer is buffered, except if it is already a *bufio.Writer (that is not flushed by Flush).
+func WithNoBuffer() Option {
+	return func(w *writer) {
+		w.noBuffer = true
+	}
+}
+
+// WithPadRows makes NewRow() append empty fields to the rows with less than n fields.
+// Writing more than n fields in a row sets an error.
+// Empty rows and comments are not affected.
+func WithPadRows(n int) Option {
+	return func(w *writer) {
+		w.padRows = n
 	}
 }
 
 // WithEnquoteAny force enquote any field.
 func WithEnquoteAny() Option {
 	return func(w *writer) {
-		w.toEnquote = func([]byte) bool { return true }
+		w.toEnquote = func(int, []byte) bool { return true }
 	}
 }
 
 // WithEnquoteMinimal enquote only if necessary.
 func WithEnquoteMinimal() Option {
 	return func(w *writer) {
-		w.toEnquote = func(data []byte) bool {
+		w.toEnquote = func(_ int, data []byte) bool {
 			return w.hasQuoteSep(data)
 		}
 	}
 }
 
+// WithEnquoteNever never enquote (nor escape) the fields, which is the fastest mode.
+// The caller is responsible for the fields to contain no separator, quote, newline or carriage return.
+// Use WithEnquoteNeverChecked to check this during testing.
+func WithEnquoteNever() Option {
+	return func(w *writer) {
+		w.toEnquote = func(int, []byte) bool { return false }
+	}
+}
+
+// WithEnquoteNeverChecked is WithEnquoteNever but a field that should be enquoted sets an error
+// (and is not written). It is useful for testing, before switching to WithEnquoteNever.
+func WithEnquoteNeverChecked() Option {
+	return func(w *writer) {
+		w.toEnquote = func(_ int, data []byte) bool {
+			if w.hasQuoteSep(data) && w.err == nil {
+				w.err = fmt.Errorf("field %q should be enquoted", data)
+			}
+			return false
+		}
+	}
+}
+
+// WithEnquoteFunc enquote the fields for which fn returns true.
+// The column index col starts at 0 for each row, and field is the data to write
+// (after the newlines escaping of WithEscapeNewlines).
+// fn is called once for each field, before WithQuoteAmbiguousNumbers and WithQuoteEdgeSpaces
+// that could still enquote the field.
+// As for WithEnquoteNever, a field that is not enquoted is written as is (or escaped with WithEscapeOnly or WithEscapedSeparators),
+// so the caller is responsible for the output to be valid if fn returns false for a field containing
+// a separator, a quote, a newline or a carriage return.
+func WithEnquoteFunc(fn func(col int, field []byte) bool) Option {
+	return func(w *writer) {
+		w.toEnquote = fn
+	}
+}
+
+// WithTrailingSeparator makes NewRow write a separator at the end of each row with at least one field (like "a,b,c,\n").
+// The empty rows and the comments are not changed.
+// Such rows could be read back with the scanner option WithIgnoreTrailingSeparator.
+func WithTrailingSeparator() Option {
+	return func(w *writer) {
+		w.trailingSep = true
+	}
+}
+
+// WithChecksumComment makes Finalize write a last comment "checksum: <hex>" with the sum of the data rows computed by h
+// (like crc32.NewIEEE() or sha256.New()). The comments and the blank rows (see scanner.IsBlankRow) are not part of the sum.
+// The sum does not depend on the quoting: each field value is followed by the byte 0x1F and each row by the byte 0x1E.
+// Such output could be checked with scanner.VerifyChecksum.
+func WithChecksumComment(h hash.Hash) Option {
+	return func(w *writer) {
+		w.checksum = h
+	}
+}
+
+// WithFloatFormat sets the format of the floats written by WriteFloatField with fmt 0 and by WriteStructs,
+// as for strconv.FormatFloat (default 'g' and -1, the smallest precision that represents the value exactly).
+// It does not change the fields written as strings or bytes.
+func WithFloatFormat(fmt byte, prec int) Option {
+	return func(w *writer) {
+		w.floatFmt = fmt
+		w.floatPrec = prec
+	}
+}
+
+// WithQuoteAmbiguousNumbers enquote the fields that spreadsheets would alter when reading them as numbers,
+// whatever the enquote mode is (see isAmbiguousNumber).
+func WithQuoteAmbiguousNumbers() Option {
+	return func(w *writer) {
+		w.quoteNumbers = true
+	}
+}
+
+// WithQuoteEdgeSpaces enquote the fields that start or end with a space or a tab, whatever the enquote mode is.
+// Readers that trim the spaces around the fields (like the fuzzy quoting of the scanner) keep them only if enquoted.
+func WithQuoteEdgeSpaces() Option {
+	return func(w *writer) {
+		w.quoteEdges = true
+	}
+}
+
+// WithCanonical writes a canonical form of the data, that is the same for the same logical data,
+// to reduce the differences between versions of a file (like in a version control system):
+//   - the fields are enquoted only if necessary (like WithEnquoteMinimal), and the separators are never escaped;
+//   - the line endings inside the fields ("\r\n" and '\r') are written as '\n', as the row terminators;
+//   - the trailing spaces and tabs of the fields that are not enquoted are removed;
+//   - the rows have no trailing separator.
+//
+// Finalize should be called at the end, to terminate the last row with a single '\n'.
+// The later options could change some of these settings (like WithQuoteEdgeSpaces that keeps the edge spaces).
+func WithCanonical() Option {
+	return func(w *writer) {
+		WithEnquoteMinimal()(w)
+		w.escapeSeps = false
+		w.escapeOnly = false
+		w.escapeNewlines = false
+		w.trailingSep = false
+		w.canonical = true
+	}
+}
+
+// hasEdgeSpace returns true if data starts or ends with a space or a tab.
+func hasEdgeSpace(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	first, last := data[0], data[len(data)-1]
+	return first == ' ' || first == '\t' || last == ' ' || last == '\t'
+}
+
+// maxSafeDigits is the max number of digits of an integer
+// that can be read by spreadsheets without loosing precision.
+const maxSafeDigits = 15
+
+// isAmbiguousNumber returns true if data has only digits and
+// starts with a leading zero (like "007") or has more than maxSafeDigits digits.
+// Such fields are reinterpreted as numbers by spreadsheets
+// and lose their leading zeros or their last digits.
+func isAmbiguousNumber(data []byte) bool {
+	if len(data) < 2 {
+		return false
+	}
+	for _, c := range data {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return data[0] == '0' || len(data) > maxSafeDigits
+}
+
 // WithEnquoteNonNumeric enquote all non-numeric fields.
 // TODO: implement
 
@@ -118,6 +483,9 @@ func (w *writer) validate() {
 	if w.escape == '\n' || w.escape == '\r' || w.escape == w.sep {
 		w.err = errors.New("escape character cannot be newline or carriage return or same as the separator")
 	}
+	if w.escapeSeps && w.escape == w.quote {
+		w.err = errors.New("escape character should be different from the quote character to escape the separators")
+	}
 	if w.sep == '\n' || w.sep == '\r' {
 		w.err = errors.New("separator character cannot be newline or carriage return")
 	}
@@ -136,28 +504,78 @@ func (w *writer) options(options ...Option) {
 	w.validate()
 }
 
-// DefaultOptions are the default options for a writer.
+// DefaultOptions are the default options for a writer, applied by New before the custom ones.
+// The options are applied in order, so the later options override the earlier ones.
+// Use Defaults to get a copy of these options with some overrides.
 var DefaultOptions = []Option{
 	WithSeparator(','),
 	WithQuote('"'),
 	WithEnquoteMinimal(),
-	WithComment([]byte("# ")),
+	withDefaultComment(),
+}
+
+// Defaults returns a new slice with the DefaultOptions followed by the overrides,
+// that could be passed to New or modified without changing DefaultOptions.
+func Defaults(overrides ...Option) []Option {
+	return append(append([]Option(nil), DefaultOptions...), overrides...)
 }
 
 // New returns a new Writer that writes to w.
 func New(w io.Writer, opts ...Option) Writer {
 	csvw := &writer{
-		bufw:       bufio.NewWriter(w),
 		atRowStart: true,
+		floatFmt:   'g',
+		floatPrec:  -1,
 	}
 	csvw.options(DefaultOptions...)
 	csvw.options(opts...)
+	csvw.setOutput(w)
 	return csvw
 }
 
+// bufferSize is the size from which the buffered rows are written to the io.Writer given to New.
+const bufferSize = 4096
+
+// setOutput sets the writer of the rows to dst.
+// Each terminated row is written to a *bufio.Writer (that is not flushed by Flush), as to dst with WithNoBuffer.
+func (w *writer) setOutput(dst io.Writer) {
+	_, buffered := dst.(*bufio.Writer)
+	w.out, w.direct = dst, buffered || w.noBuffer
+}
+
+// Reset makes the writer write to dst, as if it was returned by New with the same options.
+// The data not flushed to the previous io.Writer (including the current row) is discarded,
+// so Flush should be called before to keep it. The error, the header set by WriteHeaderRecord
+// and the counters (like the offsets of WithRowHook) are reset too.
+// It allows to reuse a writer for many outputs.
+func (w *writer) Reset(dst io.Writer) {
+	w.setOutput(dst)
+	w.err = nil
+	w.buf = w.buf[:0]
+	w.rowStart = 0
+	w.lastRow = w.lastRow[:0]
+	w.rowSum = w.rowSum[:0]
+	if w.checksum != nil {
+		w.checksum.Reset()
+	}
+	w.atRowStart = true
+	w.cols = 0
+	w.columns = 0
+	w.rows = 0
+	w.written = 0
+	w.suppressed = 0
+	// keep the errors of invalid options
+	w.validate()
+}
+
 // setsqnl sets the qsnl string used by hasQuoteSep.
 // It is called after all options are processed.
+// If the separators are escaped (see WithEscapedSeparators), they do not need enquoting.
 func (w *writer) setqsnl() {
+	if w.escapeSeps {
+		w.qsnl = string([]byte{w.quote, '\n', '\r'})
+		return
+	}
 	w.qsnl = string([]byte{w.quote, w.sep, '\n', '\r'})
 }
 
@@ -166,22 +584,61 @@ func (w *writer) hasQuoteSep(data []byte) bool {
 	return bytes.ContainsAny(data, w.qsnl)
 }
 
-// write is an internal function to write data to the underlying writer and set the error.
+// write is an internal function to append data to the buffer.
 // If an error is already set, it does nothing.
 func (w *writer) write(data []byte) {
 	if w.err != nil {
 		return
 	}
-	_, w.err = w.bufw.Write(data)
+	w.buf = append(w.buf, data...)
 }
 
-// writeByte is an internal function to write a byte to the underlying writer and set the error.
+// writeByte is an internal function to append a byte to the buffer.
 // If an error is already set, it does nothing.
 func (w *writer) writeByte(c byte) {
 	if w.err != nil {
 		return
 	}
-	w.err = w.bufw.WriteByte(c)
+	w.buf = append(w.buf, c)
+}
+
+// commitRow is called after a row (or a comment line) is terminated in the buffer, so the next row starts.
+// The buffer is written to the underlying writer if it is full (or at each row if direct).
+// If an error is already set, it does nothing.
+func (w *writer) commitRow() {
+	if w.err != nil {
+		return
+	}
+	if w.direct || len(w.buf) >= bufferSize {
+		w.flushBuffer()
+	}
+	w.rowStart = len(w.buf)
+}
+
+// flushBuffer writes the buffer to the underlying writer and set the error (if not already set).
+// The data written before an error is still written, but only once.
+func (w *writer) flushBuffer() {
+	if len(w.buf) == 0 {
+		return
+	}
+	n, err := w.out.Write(w.buf)
+	if w.err == nil {
+		w.err = err
+	}
+	w.written += int64(n)
+	w.buf = w.buf[:0]
+}
+
+// endRow is called after a row terminator is committed.
+// It counts the rows and calls the row hook.
+func (w *writer) endRow() {
+	if w.err != nil {
+		return
+	}
+	if w.rowHook != nil {
+		w.rowHook(w.rows, w.written+int64(len(w.buf)))
+	}
+	w.rows++
 }
 
 // writeEscaped writes data to the underlying writer with escaped quote characters.
@@ -201,50 +658,278 @@ func (w *writer) writeEscaped(b []byte) {
 }
 
 // WriteByteField writes a single CSV record to w along with any necessary quoting and escaping.
+// If WithPadRows(n) is used and n fields are already written in the row, an error is set.
 func (w *writer) WriteByteField(field []byte) {
-	if !w.atRowStart {
-		w.writeByte(w.sep)
+	if w.padRows > 0 && w.cols >= w.padRows {
+		if w.err == nil {
+			w.err = fmt.Errorf("row has more than %d fields", w.padRows)
+		}
+		return
+	}
+	if w.columns > 0 && w.cols >= w.columns {
+		if w.err == nil {
+			w.err = fmt.Errorf("row has more than the %d fields of the header", w.columns)
+		}
+		return
 	}
+	sep := !w.atRowStart
 	w.atRowStart = false
-	if w.toEnquote(field) {
+	w.cols++
+	if w.escapeNewlines && bytes.ContainsAny(field, "\n\r") {
+		field = w.escapeNewlinesIn(field)
+	}
+	if w.canonical && bytes.IndexByte(field, '\r') >= 0 {
+		field = w.normalizeNewlinesIn(field)
+	}
+	// the fields are never enquoted with WithEscapeOnly
+	enquote := !w.escapeOnly && (w.forceQuote || w.toEnquote(w.cols-1, field) || (w.quoteNumbers && isAmbiguousNumber(field)) || (w.quoteEdges && hasEdgeSpace(field)))
+	if w.canonical && !enquote {
+		field = bytes.TrimRight(field, " \t")
+		enquote = !w.escapeOnly && w.quoteNumbers && isAmbiguousNumber(field)
+	}
+	// the separator is written after toEnquote, so nothing is written for a field rejected by WithEnquoteNeverChecked
+	if sep {
+		w.writeByte(w.sep)
+	}
+	if w.checksum != nil {
+		w.rowSum = append(append(w.rowSum, field...), 0x1F)
+	}
+	if enquote {
 		w.writeByte(w.quote)
 		w.writeEscaped(field)
 		w.writeByte(w.quote)
+	} else if w.escapeOnly {
+		w.writeEscapeOnly(field)
+	} else if w.escapeSeps {
+		w.writeEscapedSeparators(field)
 	} else {
 		w.write(field)
 	}
 }
 
+// writeEscapedSeparators writes data with escaped separators and escape characters.
+func (w *writer) writeEscapedSeparators(b []byte) {
+	for _, c := range b {
+		if c == w.sep || c == w.escape {
+			w.writeByte(w.escape)
+		}
+		w.writeByte(c)
+	}
+}
+
+// writeEscapeOnly writes data with escaped separators, escape characters, newlines and carriage returns.
+func (w *writer) writeEscapeOnly(b []byte) {
+	for _, c := range b {
+		switch c {
+		case w.sep, w.escape:
+			w.writeByte(w.escape)
+		case '\n':
+			w.writeByte(w.escape)
+			c = 'n'
+		case '\r':
+			w.writeByte(w.escape)
+			c = 'r'
+		}
+		w.writeByte(c)
+	}
+}
+
+// escapeNewlinesIn returns field with '\n' and '\r' replaced by `\n` and `\r`.
+// The result is stored in an internal buffer to avoid allocations.
+func (w *writer) escapeNewlinesIn(field []byte) []byte {
+	w.nlbuf = w.nlbuf[:0]
+	for _, c := range field {
+		switch c {
+		case '\n':
+			w.nlbuf = append(w.nlbuf, '\\', 'n')
+		case '\r':
+			w.nlbuf = append(w.nlbuf, '\\', 'r')
+		default:
+			w.nlbuf = append(w.nlbuf, c)
+		}
+	}
+	return w.nlbuf
+}
+
+// normalizeNewlinesIn returns field with "\r\n" and '\r' replaced by '\n'.
+// The result is written in an internal buffer that is reused by the next calls.
+func (w *writer) normalizeNewlinesIn(field []byte) []byte {
+	w.nlbuf = w.nlbuf[:0]
+	for i, c := range field {
+		if c == '\r' {
+			if i+1 < len(field) && field[i+1] == '\n' {
+				continue
+			}
+			c = '\n'
+		}
+		w.nlbuf = append(w.nlbuf, c)
+	}
+	return w.nlbuf
+}
+
+// WriteScannedField writes a field read by a scanner (like Scanner.Bytes()),
+// enquoted if wasQuoted is true (like Scanner.IsQuoted()), or if the enquote mode requires it.
+// It could be used to pass the unchanged fields through, keeping their quoting.
+// The output is identical to the input only if the scanner and the writer use the same quote and escape characters
+// (the escaped quotes are written as the writer escapes them), and without spaces around the quotes.
+func (w *writer) WriteScannedField(field []byte, wasQuoted bool) {
+	w.forceQuote = wasQuoted
+	w.WriteByteField(field)
+	w.forceQuote = false
+}
+
 // WriteStringField writes a single CSV record to w along with any necessary quoting and escaping.
 func (w *writer) WriteStringField(field string) {
 	w.WriteByteField([]byte(field))
 }
 
+// WriteIntField writes a single integer CSV field.
+// The field is formatted in an internal buffer to avoid allocations.
+func (w *writer) WriteIntField(field int64) {
+	w.numbuf = strconv.AppendInt(w.numbuf[:0], field, 10)
+	w.WriteByteField(w.numbuf)
+}
+
+// WriteFloatField writes a single float CSV field formatted as strconv.FormatFloat(field, fmt, prec, 64).
+// If fmt is 0, the format and the precision set by WithFloatFormat are used.
+// The field is formatted in an internal buffer to avoid allocations.
+func (w *writer) WriteFloatField(field float64, fmt byte, prec int) {
+	if fmt == 0 {
+		fmt, prec = w.floatFmt, w.floatPrec
+	}
+	w.numbuf = strconv.AppendFloat(w.numbuf[:0], field, fmt, prec, 64)
+	w.WriteByteField(w.numbuf)
+}
+
+// WriteBoolField writes a single boolean CSV field ("true" or "false").
+func (w *writer) WriteBoolField(field bool) {
+	w.numbuf = strconv.AppendBool(w.numbuf[:0], field)
+	w.WriteByteField(w.numbuf)
+}
+
+// WriteRecord writes the fields of a record and terminates the row (see NewRow).
+func (w *writer) WriteRecord(record []string) {
+	for _, field := range record {
+		w.WriteStringField(field)
+	}
+	w.NewRow()
+}
+
+// WriteHeaderRecord writes the header as a record, and then all the rows should have the same number of fields,
+// else an error is set (by the extra field or by NewRow for a short row).
+// Empty rows and comments are still allowed.
+func (w *writer) WriteHeaderRecord(header []string) {
+	w.WriteRecord(header)
+	w.columns = len(header)
+}
+
 // NewRow writes the end-of-line marker only if not at the beginning of a line.
+// If WithPadRows(n) is used, empty fields are added to have n fields.
 func (w *writer) NewRow() {
 	if !w.atRowStart {
+		for w.cols < w.padRows {
+			w.WriteByteField(nil)
+		}
+		if w.cols < w.columns && w.err == nil {
+			w.err = fmt.Errorf("row has %d fields instead of the %d fields of the header", w.cols, w.columns)
+		}
+		if w.trailingSep {
+			w.writeByte(w.sep)
+		}
 		w.writeByte('\n')
+		switch {
+		case w.dedup && w.err == nil && w.rowStart >= 0 && bytes.Equal(w.buf[w.rowStart:], w.lastRow):
+			w.buf = w.buf[:w.rowStart]
+			w.suppressed++
+		case w.dedup && w.rowStart >= 0:
+			w.lastRow = append(w.lastRow[:0], w.buf[w.rowStart:]...)
+			fallthrough
+		default:
+			w.commitRow()
+			w.endRow()
+			// a blank row is an empty line for the readers
+			if w.checksum != nil && !w.blankRow() {
+				w.checksum.Write(append(w.rowSum, 0x1E))
+			}
+		}
+		w.rowSum = w.rowSum[:0]
 	}
 	w.atRowStart = true
+	w.cols = 0
+}
+
+// blankRow returns true if the current row is blank for the checksum (see scanner.IsBlankRow).
+func (w *writer) blankRow() bool {
+	return w.cols == 0 || w.cols == 1 && scanner.IsBlankRow([][]byte{w.rowSum[:len(w.rowSum)-1]})
+}
+
+// SuppressedRows returns the number of rows not written because of WithDedupConsecutiveRows.
+func (w *writer) SuppressedRows() int {
+	return w.suppressed
+}
+
+// AbortRow drops the fields written in the current row (since the last NewRow),
+// so the writer is again at the row start.
+// If Flush was called in the middle of the row, its start is already written
+// and can not be dropped, so an error is set.
+func (w *writer) AbortRow() {
+	if w.rowStart < 0 {
+		if w.err == nil {
+			w.err = errors.New("row cannot be aborted after a Flush in its middle")
+		}
+		return
+	}
+	w.buf = w.buf[:w.rowStart]
+	w.rowSum = w.rowSum[:0]
+	w.atRowStart = true
+	w.cols = 0
 }
 
 // writeCommentLine writes a comment line followed by the end-of-line marker.
 func (w *writer) writeCommentLine(data []byte) {
-	if !w.atRowStart {
-		w.writeByte('\n')
+	w.NewRow()
+	if w.blankComment && len(bytes.Trim(data, " \t")) == 0 {
+		// an empty line without prefix
+		data = nil
+	} else if w.rawComment && w.defaultComment {
+		w.write(w.comment[:len(w.comment)-1])
+	} else {
+		w.write(w.comment)
 	}
-	w.write(w.comment)
 	w.write(data)
 	w.writeByte('\n')
+	w.commitRow()
+	w.lastRow = w.lastRow[:0]
 	w.atRowStart = true
 }
 
 // WriteByteComment writes a sequence of comment lines followed by the end-of-line marker.
+// In strict comments mode, if not at the beginning of a row, an error is set and nothing is written.
 func (w *writer) WriteByteComment(comment []byte) {
-	comment = bytes.TrimRight(comment, "\r\n\t ")
+	if w.strictComments && !w.atRowStart {
+		if w.err == nil {
+			w.err = errors.New("comment cannot be written in the middle of a row")
+		}
+		return
+	}
+	if w.rawComment {
+		comment = bytes.TrimRight(comment, "\r\n")
+	} else {
+		comment = bytes.TrimRight(comment, "\r\n\t ")
+	}
 	lines := bytes.Split(comment, []byte{'\n'})
 	for _, line := range lines {
-		line = bytes.Trim(line, "\r")
+		if w.rawComment {
+			line = bytes.TrimSuffix(line, []byte{'\r'})
+		} else {
+			line = bytes.Trim(line, "\r")
+		}
+		if w.commentWrap > 0 {
+			for _, l := range wrapLine(line, w.commentWrap-utf8.RuneCount(w.comment)) {
+				w.writeCommentLine(l)
+			}
+			continue
+		}
 		w.writeCommentLine(line)
 	}
 }
@@ -256,10 +941,22 @@ func (w *writer) WriteStringComment(comment string) {
 
 // EmptyRow writes an empty row.
 func (w *writer) EmptyRow() {
-	if !w.atRowStart {
-		w.writeByte('\n')
-	}
+	w.NewRow()
 	w.writeByte('\n')
+	w.commitRow()
+	w.endRow()
+	w.lastRow = w.lastRow[:0]
+	w.atRowStart = true
+}
+
+// WritePreamble writes raw as it is, after terminating the current row if needed.
+// It could be used to keep the preamble of a file (see sniffer.LenPreamble) when rewriting it.
+// The writer stays at the row start, so raw should end with a newline.
+func (w *writer) WritePreamble(raw []byte) {
+	w.NewRow()
+	w.write(raw)
+	w.commitRow()
+	w.lastRow = w.lastRow[:0]
 	w.atRowStart = true
 }
 
@@ -268,15 +965,49 @@ func (w *writer) Error() error {
 	return w.err
 }
 
-// Flush writes any buffered data to the underlying io.Writer.
+// Flush writes any buffered data to the underlying io.Writer,
+// including the current row if it is not terminated, and the data written before an error.
+// If the io.Writer given to New is a *bufio.Writer, the data is written to it, but it is not flushed.
 func (w *writer) Flush() {
-	if w.err != nil {
+	w.flushBuffer()
+	if w.atRowStart {
+		w.rowStart = 0
 		return
 	}
-	w.err = w.bufw.Flush()
+	// the rest of the row can not be compared to the previous row, nor dropped
+	w.rowStart = -1
+	w.lastRow = w.lastRow[:0]
+}
+
+// Finalize terminates the current row and, if WithChecksumComment is used,
+// writes the comment "checksum: <hex>" with the sum of the data rows written so far.
+// Then the data is flushed. It should be called once, after the last row.
+func (w *writer) Finalize() {
+	w.NewRow()
+	if w.checksum != nil {
+		// written on a single line, even with WithCommentWrap
+		w.writeCommentLine([]byte("checksum: " + hex.EncodeToString(w.checksum.Sum(nil))))
+	}
+	w.Flush()
 }
 
 // AtRowStart returns true if the writer is at the start of a row.
 func (w *writer) AtRowStart() bool {
 	return w.atRowStart
 }
+
+// EscapeByteField returns the field as it would be written by a writer configured with opts,
+// ie enquoted and escaped if necessary.
+func EscapeByteField(field []byte, opts ...Option) []byte {
+	var buf bytes.Buffer
+	w := New(&buf, opts...)
+	w.WriteByteField(field)
+	w.Flush()
+	return buf.Bytes()
+}
+
+// EscapeField returns the field as it would be written by a writer configured with opts,
+// ie enquoted and escaped if necessary.
+func EscapeField(field string, opts ...Option) string {
+	return string(EscapeByteField([]byte(field), opts...))
+}